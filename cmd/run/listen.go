@@ -0,0 +1,158 @@
+package main
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/abakunov/log-analyzer/internal/infrastructure"
+
+	"github.com/spf13/cobra"
+)
+
+// reportFormats are the values of the format query parameter of the report endpoint.
+var reportFormats = []string{"plain", "markdown", "adoc"}
+
+var (
+	syslogAddresses []string
+	listenInterval  time.Duration
+	listenHTTP      string
+)
+
+// setupListenCmd initializes the listen command, which receives logs over syslog.
+func setupListenCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "listen",
+		Short: "Receive logs over syslog, such as NGINX access_log syslog: output, and report on demand.",
+		Run: func(_ *cobra.Command, _ []string) {
+			runListen()
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&syslogAddresses, "syslog", nil,
+		"Address to receive syslog messages on, udp://host:port or tcp://host:port; repeatable (required).")
+	cmd.Flags().DurationVar(&listenInterval, "interval", 0,
+		"How often the report is printed; 0 prints it only on SIGUSR1 and on exit (optional).")
+	cmd.Flags().StringVar(&listenHTTP, "http", "",
+		"Address of an HTTP endpoint serving the current report, such as :8080; ?format=markdown or adoc (optional).")
+	addInputFlags(cmd)
+
+	err := cmd.MarkFlagRequired("syslog")
+	if err != nil {
+		log.Fatalf("Error marking syslog flag as required: %v", err)
+	}
+
+	return cmd
+}
+
+// runListen receives syslog messages until interrupted, printing the plain report on every interval,
+// on every report signal and once more on exit, and serving it over HTTP if requested.
+func runListen() {
+	fromTime, toTime, err := infrastructure.ParseTimeBounds(from, to)
+	if err != nil {
+		log.Fatalf("Error parsing time bounds: %v", err)
+	}
+
+	parser, formats, err := buildParser()
+	if err != nil {
+		log.Fatalf("Error selecting log format: %v", err)
+	}
+
+	analyzer := application.NewLogAnalyzer([]string{application.SyslogSource}, parser)
+	analyzer.Formats = formats
+	configureReport(analyzer)
+
+	receivers := make([]*application.SyslogReceiver, 0, len(syslogAddresses))
+
+	for _, address := range syslogAddresses {
+		receiver, err := application.NewSyslogReceiver(address)
+		if err != nil {
+			log.Fatalf("Error starting syslog receiver: %v", err)
+		}
+
+		fmt.Printf("Receiving syslog messages on %s://%s\n", receiver.Addr().Network(), receiver.Addr())
+
+		receivers = append(receivers, receiver)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	signals := make(chan os.Signal, 1)
+	if len(reportSignals) > 0 {
+		signal.Notify(signals, reportSignals...)
+		defer signal.Stop(signals)
+	}
+
+	queries := make(chan func(*domain.Metrics))
+
+	if listenHTTP != "" {
+		server := &http.Server{Addr: listenHTTP, Handler: reportHandler(ctx, queries), ReadHeaderTimeout: 10 * time.Second}
+
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Fatalf("Error serving report: %v", err)
+			}
+		}()
+
+		defer server.Close()
+	}
+
+	output := infrastructure.ReportOutput{}
+	printReport := func(metrics *domain.Metrics) {
+		formatter := infrastructure.ReportFormatter{Metrics: metrics}
+		output.OutputToConsole(formatter.Render("plain"))
+	}
+
+	err = analyzer.Listen(ctx, receivers, fromTime, toTime, filterField, filterValue, application.ListenOptions{
+		ReportInterval: listenInterval,
+		Signals:        signals,
+		Report:         printReport,
+		Queries:        queries,
+	})
+	if err != nil {
+		log.Fatalf("Error receiving logs: %v", err)
+	}
+
+	printReport(analyzer.Metrics)
+}
+
+// reportHandler serves the report of the metrics collected so far. The report is rendered by the
+// goroutine that owns the metrics, which runs the functions sent to queries until ctx is done.
+func reportHandler(ctx context.Context, queries chan<- func(*domain.Metrics)) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		format := cmp.Or(r.URL.Query().Get("format"), "plain")
+		if !slices.Contains(reportFormats, format) {
+			http.Error(w, "unsupported format "+format, http.StatusBadRequest)
+			return
+		}
+
+		rendered := make(chan string, 1)
+		render := func(metrics *domain.Metrics) {
+			formatter := infrastructure.ReportFormatter{Metrics: metrics}
+			rendered <- formatter.Render(format)
+		}
+
+		select {
+		case queries <- render:
+		case <-ctx.Done():
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+			return
+		case <-r.Context().Done():
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		_, _ = w.Write([]byte(<-rendered))
+	})
+}
@@ -1,24 +1,54 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
 	"github.com/abakunov/log-analyzer/internal/infrastructure"
 
 	"github.com/spf13/cobra"
 )
 
 var (
-	globPattern string
-	from        string
-	to          string
-	format      string
-	filterField string
-	filterValue string
-	rootCmd     *cobra.Command
+	globPatterns []string
+	excludes     []string
+	skipHidden   bool
+	skipBinary   bool
+	from         string
+	to           string
+	format       string
+	filterField  string
+	filterValue  string
+	logFormat    string
+	nginxFormat  string
+	nginxConf    string
+	jsonMapping  string
+	workers      int
+	parseWorkers int
+	stateFile    string
+	httpHeaders  []string
+	bearerToken  string
+	basicAuth    string
+	clientCert   string
+	clientKey    string
+	caCert       string
+	httpTimeout  time.Duration
+	httpRetries  int
+	s3Endpoint   string
+	s3Region     string
+	s3Profile    string
+	bucketWidth  time.Duration
+	normalize    []string
+	routes       []string
+	rootCmd      *cobra.Command
 )
 
 // setupRootCmd initializes the root command and its flags.
@@ -31,21 +61,94 @@ func setupRootCmd() *cobra.Command {
 		},
 	}
 
-	cmd.Flags().StringVar(&globPattern, "path", "", "Path(s) to log files (required).")
-	cmd.Flags().StringVar(&from, "from", "", "Start date in ISO8601 format (optional).")
-	cmd.Flags().StringVar(&to, "to", "", "End date in ISO8601 format (optional).")
+	cmd.Flags().StringArrayVar(&globPatterns, "path", nil,
+		"Path, glob (** matches any number of directories), URL or s3:// path of log files; repeatable (required).")
+	cmd.Flags().StringArrayVar(&excludes, "exclude", nil,
+		"Glob of files and directories to skip, matched against names or, with a slash, paths; repeatable (optional).")
+	cmd.Flags().BoolVar(&skipHidden, "skip-hidden", false, "Skip files and directories whose names start with a dot (optional).")
+	cmd.Flags().BoolVar(&skipBinary, "skip-binary", false, "Skip binary files other than compressed files and archives (optional).")
 	cmd.Flags().StringVar(&format, "format", "", "Output format: markdown or adoc (optional).")
-	cmd.Flags().StringVar(&filterField, "filter-field", "", "Field to filter logs by (optional).")
-	cmd.Flags().StringVar(&filterValue, "filter-value", "", "Value to filter logs by (supports glob patterns, optional).")
+	cmd.Flags().IntVar(&workers, "workers", runtime.NumCPU(), "Number of sources analyzed concurrently (optional).")
+	cmd.Flags().IntVar(&parseWorkers, "parse-workers", runtime.NumCPU(),
+		"Number of goroutines parsing the lines of a single source (optional).")
+	cmd.Flags().StringVar(&stateFile, "state", "",
+		"State file to continue from: only new lines of local files are analyzed and added to the stored metrics (optional).")
+	addInputFlags(cmd)
+	addHTTPFlags(cmd)
 
 	err := cmd.MarkFlagRequired("path")
 	if err != nil {
 		log.Fatalf("Error marking path flag as required: %v", err)
 	}
 
+	cmd.AddCommand(setupTailCmd())
+	cmd.AddCommand(setupListenCmd())
+
 	return cmd
 }
 
+// addInputFlags adds the flags selecting the log format, filtering the records and grouping them in
+// the report, shared by all commands.
+func addInputFlags(cmd *cobra.Command) {
+	cmd.Flags().StringVar(&from, "from", "", "Start date in ISO8601 format (optional).")
+	cmd.Flags().StringVar(&to, "to", "", "End date in ISO8601 format (optional).")
+	cmd.Flags().StringVar(&filterField, "filter-field", "", "Field to filter logs by (optional).")
+	cmd.Flags().StringVar(&filterValue, "filter-value", "", "Value to filter logs by (supports glob patterns, optional).")
+	cmd.Flags().StringVar(&logFormat, "log-format", application.AutoDetectFormat,
+		fmt.Sprintf("Log format of the input: %s (detect per source), %s, or a log_format name from --nginx-conf (optional).",
+			application.AutoDetectFormat, strings.Join(application.ParserNames(), ", ")))
+	cmd.Flags().StringVar(&nginxFormat, "nginx-log-format", "", "Raw NGINX log_format string describing the input (optional).")
+	cmd.Flags().StringVar(&jsonMapping, "json-mapping", "",
+		fmt.Sprintf("Mapping of JSON log keys to fields, e.g. \"ip=client.addr,timestamp=@timestamp\"; fields: %s (optional).",
+			strings.Join(application.JSONFields, ", ")))
+	cmd.Flags().StringVar(&nginxConf, "nginx-conf", "", "Path to nginx.conf to take the --log-format definition from (optional).")
+	cmd.Flags().DurationVar(&bucketWidth, "bucket", 0,
+		"Width of the timeline buckets, such as 1m, 5m or 1h; chosen from the time span of the logs by default (optional).")
+	cmd.Flags().StringSliceVar(&normalize, "normalize", nil,
+		fmt.Sprintf("URL normalization before resources are counted: %s or all, comma-separated (optional).",
+			strings.Join(application.NormalizeOptions, ", ")))
+	cmd.Flags().StringArrayVar(&routes, "route", nil,
+		"Route pattern such as /users/:id/orders that matching URLs are counted under; repeatable (optional).")
+}
+
+// addHTTPFlags adds the flags configuring the download of URL sources.
+func addHTTPFlags(cmd *cobra.Command) {
+	cmd.Flags().StringArrayVar(&httpHeaders, "header", nil, "HTTP header \"Name: value\" sent with URL requests, repeatable (optional).")
+	cmd.Flags().StringVar(&bearerToken, "bearer-token", "", "Bearer token for URL requests (optional).")
+	cmd.Flags().StringVar(&basicAuth, "basic-auth", "", "Basic auth credentials \"user:password\" for URL requests (optional).")
+	cmd.Flags().StringVar(&clientCert, "client-cert", "", "PEM client certificate for URL requests (optional).")
+	cmd.Flags().StringVar(&clientKey, "client-key", "", "PEM private key of --client-cert (optional).")
+	cmd.Flags().StringVar(&caCert, "ca-cert", "", "PEM bundle of extra certificate authorities for URL requests (optional).")
+	cmd.Flags().DurationVar(&httpTimeout, "http-timeout", 30*time.Second,
+		"Timeout for the response headers and for every read of a URL source, 0 for none (optional).")
+	cmd.Flags().IntVar(&httpRetries, "http-retries", 5,
+		"Number of retries of a failed URL download; interrupted downloads continue from the last byte (optional).")
+	cmd.Flags().StringVar(&s3Endpoint, "s3-endpoint", "",
+		"Endpoint of S3-compatible storage for s3:// paths, such as http://localhost:9000 for MinIO; AWS S3 by default (optional).")
+	cmd.Flags().StringVar(&s3Region, "s3-region", "", "Region of s3:// paths, taken from the AWS environment by default (optional).")
+	cmd.Flags().StringVar(&s3Profile, "s3-profile", "", "Profile of the shared AWS credentials file for s3:// paths (optional).")
+}
+
+// buildHTTPConfig creates the download settings of URL sources from the HTTP flags.
+func buildHTTPConfig() (application.HTTPConfig, error) {
+	header, err := infrastructure.ParseHTTPHeaders(httpHeaders, bearerToken, basicAuth)
+	if err != nil {
+		return application.HTTPConfig{}, err
+	}
+
+	client, err := infrastructure.NewHTTPClient(clientCert, clientKey, caCert)
+	if err != nil {
+		return application.HTTPConfig{}, err
+	}
+
+	return application.HTTPConfig{
+		Client:  client,
+		Header:  header,
+		Timeout: httpTimeout,
+		Retries: httpRetries,
+	}, nil
+}
+
 // runAnalyzer handles the log analysis process by parsing inputs and generating reports.
 func runAnalyzer() {
 	fromTime, toTime, err := infrastructure.ParseTimeBounds(from, to)
@@ -53,20 +156,71 @@ func runAnalyzer() {
 		log.Fatalf("Error parsing time bounds: %v", err)
 	}
 
-	paths, err := infrastructure.ParseFiles(globPattern)
+	filter := application.SourceFilter{Exclude: excludes, SkipHidden: skipHidden, SkipBinary: skipBinary}
+
+	paths, err := infrastructure.ParsePaths(globPatterns, filter)
 
 	if err != nil {
 		log.Fatalf("Error parsing files: %v", err)
 	}
 
-	analyzer := application.NewLogAnalyzer(paths)
+	parser, formats, err := buildParser()
+	if err != nil {
+		log.Fatalf("Error selecting log format: %v", err)
+	}
+
+	analyzer := application.NewLogAnalyzer(paths, parser)
+	analyzer.Formats = formats
+	analyzer.Workers = workers
+	analyzer.ParseWorkers = parseWorkers
+	analyzer.SourceFilter = filter
+	configureReport(analyzer)
+	configureRemoteSources(analyzer)
+
+	state := loadState(analyzer)
+
 	err = analyzer.AnalyzeLogs(fromTime, toTime, filterField, filterValue)
 
 	if err != nil {
 		log.Fatalf("Error analyzing logs: %v", err)
 	}
 
-	metrics := analyzer.Metrics
+	saveState(analyzer, state)
+
+	outputReport(analyzer.Metrics)
+}
+
+// configureRemoteSources applies the HTTP and S3 flags to the analyzer.
+func configureRemoteSources(analyzer *application.LogAnalyzer) {
+	httpConfig, err := buildHTTPConfig()
+	if err != nil {
+		log.Fatalf("Error configuring HTTP sources: %v", err)
+	}
+
+	s3Config, err := infrastructure.LoadS3Config(s3Endpoint, s3Region, s3Profile)
+	if err != nil {
+		log.Fatalf("Error configuring S3 sources: %v", err)
+	}
+
+	// Role credentials need requests to STS or the instance metadata service, so they are only
+	// looked up for s3:// paths.
+	if s3Config.Credentials.AccessKeyID == "" && slices.ContainsFunc(analyzer.Paths, application.IsS3) {
+		s3Config.Credentials, err = infrastructure.LoadS3RoleCredentials(s3Config.Region)
+		if err != nil {
+			log.Fatalf("Error configuring S3 sources: %v", err)
+		}
+
+		if s3Config.Credentials.AccessKeyID == "" {
+			fmt.Println("Warning: no AWS credentials found, S3 requests are sent unsigned")
+		}
+	}
+
+	analyzer.HTTP = httpConfig
+	analyzer.S3 = s3Config
+}
+
+// outputReport renders the report in the --format format and prints or saves it.
+func outputReport(metrics *domain.Metrics) {
 	formatter := infrastructure.ReportFormatter{Metrics: metrics}
 	output := infrastructure.ReportOutput{}
 
@@ -96,6 +250,118 @@ func runAnalyzer() {
 	}
 }
 
+// stateFilters describes the flags that select and group records, which must not change between runs sharing a state file.
+func stateFilters() string {
+	filters := fmt.Sprintf("from=%q to=%q filter-field=%q filter-value=%q", from, to, filterField, filterValue)
+
+	// Resources are counted under normalized URLs, so the normalization must not change either.
+	if len(normalize) > 0 || len(routes) > 0 {
+		filters += fmt.Sprintf(" normalize=%q routes=%q", normalize, routes)
+	}
+
+	// Buckets of a fixed width cannot be split into narrower ones, so the width must not change either.
+	if bucketWidth != 0 {
+		filters += fmt.Sprintf(" bucket=%s", bucketWidth)
+	}
+
+	return filters
+}
+
+// loadState resumes the analyzer from the --state file, if one is given.
+func loadState(analyzer *application.LogAnalyzer) *domain.State {
+	if stateFile == "" {
+		return nil
+	}
+
+	state, err := infrastructure.LoadState(stateFile)
+	if err != nil {
+		log.Fatalf("Error loading state: %v", err)
+	}
+
+	if state.Filters != "" && state.Filters != stateFilters() {
+		log.Fatalf("Error loading state: %s was written with different filters: %s", stateFile, state.Filters)
+	}
+
+	analyzer.Resume(state)
+
+	return state
+}
+
+// saveState writes the metrics and checkpoints of the analyzer to the --state file, if one is given.
+func saveState(analyzer *application.LogAnalyzer, state *domain.State) {
+	if state == nil {
+		return
+	}
+
+	state.Filters = stateFilters()
+	state.Sources = analyzer.Checkpoints
+	state.Metrics = analyzer.Metrics
+
+	if err := infrastructure.SaveState(stateFile, state); err != nil {
+		log.Fatalf("Error saving state: %v", err)
+	}
+}
+
+// configureReport applies the flags shaping the report, --bucket, --normalize and --route, to the analyzer.
+func configureReport(analyzer *application.LogAnalyzer) {
+	if bucketWidth < 0 || bucketWidth%time.Second != 0 {
+		log.Fatalf("Error parsing bucket width: %s is not a whole number of seconds", bucketWidth)
+	}
+
+	normalizer, err := application.ParseURLNormalizer(normalize, routes)
+	if err != nil {
+		log.Fatalf("Error parsing URL normalization: %v", err)
+	}
+
+	analyzer.BucketWidth = bucketWidth
+	analyzer.URLNormalizer = normalizer
+}
+
+// buildParser creates the log parser selected by the --log-format, --nginx-log-format and --nginx-conf flags,
+// and the log formats format detection chooses from, which --json-mapping changes.
+func buildParser() (domain.LogParser, application.ParserRegistry, error) {
+	formats := application.RegisteredParsers()
+
+	if jsonMapping != "" {
+		mapping, err := application.ParseJSONMapping(jsonMapping)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		formats["json"] = func() domain.LogParser { return application.NewJSONParser(mapping) }
+	}
+
+	switch {
+	case nginxFormat != "":
+		parser, err := application.NewNginxParser(nginxFormat)
+		return parser, formats, err
+	case nginxConf != "":
+		name := logFormat
+		if name == application.AutoDetectFormat {
+			name = "combined"
+		}
+
+		formatString, err := infrastructure.LoadNginxLogFormat(nginxConf, name)
+		if errors.Is(err, infrastructure.ErrLogFormatNotFound) && name == "combined" {
+			// NGINX predefines "combined", so configs usually do not declare it.
+			formatString, err = application.NginxCombinedFormat, nil
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		parser, err := application.NewNginxParser(formatString)
+
+		return parser, formats, err
+	case logFormat == application.AutoDetectFormat:
+		return nil, formats, nil // A nil parser makes the analyzer detect the format of each source.
+	default:
+		parser, err := formats.NewParser(logFormat)
+		return parser, formats, err
+	}
+}
+
 // main is the entry point of the program.
 func main() {
 	rootCmd = setupRootCmd()
@@ -47,12 +47,36 @@ func TestRunAnalyzer_Success(t *testing.T) {
 	assert.NoError(t, err, "Failed to create logfile.log.")
 
 	// Mock глобальные переменные.
-	globPattern = "testdata/logfile.log"
+	globPatterns = []string{"testdata/logfile.log"}
 	from = ""
 	to = ""
 	format = ""
 	filterField = ""
 	filterValue = ""
+	logFormat = "combined"
+	nginxFormat = ""
+	nginxConf = ""
+	jsonMapping = ""
+	workers = 1
+	parseWorkers = 1
+	stateFile = ""
+	excludes = nil
+	skipHidden = false
+	skipBinary = false
+	httpHeaders = nil
+	bearerToken = ""
+	basicAuth = ""
+	clientCert = ""
+	clientKey = ""
+	caCert = ""
+	httpTimeout = 0
+	httpRetries = 0
+	s3Endpoint = ""
+	s3Region = ""
+	s3Profile = ""
+	bucketWidth = 0
+	normalize = nil
+	routes = nil
 
 	// Capture the output of the analyzer.
 	output, err := captureOutput(func() { runAnalyzer() })
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "os"
+
+// reportSignals is empty here: there is no SIGUSR1 on this platform, so a running command cannot
+// be asked for its report with a signal.
+var reportSignals []os.Signal
@@ -0,0 +1,12 @@
+//go:build unix
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// reportSignals are the signals on which the long-running commands, such as tail and listen, print
+// their report right away instead of waiting for the next interval.
+var reportSignals = []os.Signal{syscall.SIGUSR1}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/abakunov/log-analyzer/internal/infrastructure"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	tailPath     string
+	tailInterval time.Duration
+	tailFromEnd  bool
+)
+
+// setupTailCmd initializes the tail command, which follows a growing log file.
+func setupTailCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tail",
+		Short: "Follow a log file like tail -F and periodically print the report.",
+		Run: func(_ *cobra.Command, _ []string) {
+			runTail()
+		},
+	}
+
+	cmd.Flags().StringVar(&tailPath, "path", "", "Path to the log file to follow (required).")
+	cmd.Flags().DurationVar(&tailInterval, "interval", 10*time.Second,
+		"How often the report is printed; 0 prints it only on SIGUSR1 and on exit (optional).")
+	cmd.Flags().BoolVar(&tailFromEnd, "from-end", false, "Skip the lines already in the file (optional).")
+	addInputFlags(cmd)
+
+	err := cmd.MarkFlagRequired("path")
+	if err != nil {
+		log.Fatalf("Error marking path flag as required: %v", err)
+	}
+
+	return cmd
+}
+
+// runTail follows the log file until interrupted, printing the plain report on every interval
+// and on every report signal, and once more on exit.
+func runTail() {
+	fromTime, toTime, err := infrastructure.ParseTimeBounds(from, to)
+	if err != nil {
+		log.Fatalf("Error parsing time bounds: %v", err)
+	}
+
+	parser, formats, err := buildParser()
+	if err != nil {
+		log.Fatalf("Error selecting log format: %v", err)
+	}
+
+	analyzer := application.NewLogAnalyzer([]string{tailPath}, parser)
+	analyzer.Formats = formats
+	configureReport(analyzer)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	signals := make(chan os.Signal, 1)
+	if len(reportSignals) > 0 {
+		signal.Notify(signals, reportSignals...)
+		defer signal.Stop(signals)
+	}
+
+	output := infrastructure.ReportOutput{}
+	printReport := func(metrics *domain.Metrics) {
+		formatter := infrastructure.ReportFormatter{Metrics: metrics}
+		output.OutputToConsole(formatter.Render("plain"))
+	}
+
+	err = analyzer.Follow(ctx, tailPath, fromTime, toTime, filterField, filterValue, application.FollowOptions{
+		ReportInterval: tailInterval,
+		FromEnd:        tailFromEnd,
+		Signals:        signals,
+		Report:         printReport,
+	})
+	if err != nil {
+		log.Fatalf("Error following log file: %v", err)
+	}
+
+	printReport(analyzer.Metrics)
+}
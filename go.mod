@@ -3,8 +3,10 @@ module github.com/abakunov/log-analyzer
 go 1.23.0
 
 require (
+	github.com/klauspost/compress v1.17.11
 	github.com/spf13/cobra v1.8.1
 	github.com/stretchr/testify v1.9.0
+	github.com/ulikunitz/xz v0.5.12
 )
 
 require (
@@ -15,7 +15,7 @@ fi
 #!/bin/bash
 
 echo "Building the application..."
-if go build -o analyzer cmd/run/main.go; then
+if go build -o analyzer ./cmd/run; then
     echo "Build successful. The application is now installed as 'analyzer'."
 else
     echo "Build failed. Make sure your project compiles successfully."
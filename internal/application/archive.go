@@ -0,0 +1,158 @@
+package application
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"time"
+)
+
+const (
+	// tarMagicOffset is the position of the "ustar" magic in a tar header block.
+	tarMagicOffset = 257
+)
+
+var (
+	tarMagic = []byte("ustar")
+	zipMagic = []byte("PK\x03\x04")
+)
+
+// processStream decompresses the reader and processes it either as a tar archive or as a single log source.
+func (a *LogAnalyzer) processStream(reader io.Reader, source string, from, to time.Time, filterField, filterValue string) error {
+	decompressed, err := decompress(reader)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", source, err)
+	}
+	defer decompressed.Close()
+
+	buffered := bufio.NewReaderSize(decompressed, detectSampleBytes)
+
+	if magic, _ := buffered.Peek(tarMagicOffset + len(tarMagic)); bytes.HasSuffix(magic, tarMagic) {
+		return a.processTar(buffered, source, from, to, filterField, filterValue)
+	}
+
+	return a.processLogs(buffered, source, from, to, filterField, filterValue)
+}
+
+// processTar processes every regular file of a tar archive as its own source.
+func (a *LogAnalyzer) processTar(reader io.Reader, archive string, from, to time.Time, filterField, filterValue string) error {
+	tarReader := tar.NewReader(reader)
+
+	var members []string
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read tar archive %s: %w", archive, err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		member := archive + "/" + header.Name
+		members = append(members, member)
+
+		fmt.Printf("Processing archive member: %s\n", member)
+
+		err = a.processStream(tarReader, member, from, to, filterField, filterValue)
+		if err != nil && !errors.Is(err, errSourceSkipped) {
+			return fmt.Errorf("error processing archive member %s: %w", member, err)
+		}
+	}
+
+	a.replaceFileName(archive, members)
+
+	return nil
+}
+
+// processZip processes every file of a local zip archive as its own source.
+func (a *LogAnalyzer) processZip(archive string, from, to time.Time, filterField, filterValue string) error {
+	zipReader, err := zip.OpenReader(archive)
+	if err != nil {
+		return fmt.Errorf("failed to open zip archive %s: %w", archive, err)
+	}
+	defer zipReader.Close()
+
+	var members []string
+
+	for _, file := range zipReader.File {
+		if file.FileInfo().IsDir() {
+			continue
+		}
+
+		member := archive + "/" + file.Name
+		members = append(members, member)
+
+		fmt.Printf("Processing archive member: %s\n", member)
+
+		err := a.processZipMember(file, member, from, to, filterField, filterValue)
+		if err != nil && !errors.Is(err, errSourceSkipped) {
+			return fmt.Errorf("error processing archive member %s: %w", member, err)
+		}
+	}
+
+	a.replaceFileName(archive, members)
+
+	return nil
+}
+
+// processZipMember processes a single file of a zip archive.
+func (a *LogAnalyzer) processZipMember(file *zip.File, member string, from, to time.Time, filterField, filterValue string) error {
+	reader, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", member, err)
+	}
+	defer reader.Close()
+
+	return a.processStream(reader, member, from, to, filterField, filterValue)
+}
+
+// isZipFile reports whether the local file starts with the zip magic bytes.
+func isZipFile(filePath string) bool {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	magic := make([]byte, len(zipMagic))
+	if _, err := io.ReadFull(file, magic); err != nil {
+		return false
+	}
+
+	return bytes.Equal(magic, zipMagic)
+}
+
+// isPlainFile reports whether the local file holds plain text rather than compressed data or an
+// archive, which is what allows resuming its analysis at a byte offset.
+func isPlainFile(file *os.File) bool {
+	header := make([]byte, tarMagicOffset+len(tarMagic))
+	n, _ := file.ReadAt(header, 0)
+
+	return !isCompressed(header[:n]) && !bytes.HasPrefix(header[:n], zipMagic) &&
+		!(n == len(header) && bytes.HasSuffix(header, tarMagic))
+}
+
+// replaceFileName replaces a source in Metrics.FileNames with the given names, or appends them
+// when the source is not listed, as happens for files found inside a directory.
+func (a *LogAnalyzer) replaceFileName(source string, names []string) {
+	fileNames := a.Metrics.FileNames
+
+	if i := slices.Index(fileNames, source); i >= 0 {
+		a.Metrics.FileNames = slices.Concat(fileNames[:i:i], names, fileNames[i+1:])
+		return
+	}
+
+	a.Metrics.FileNames = append(fileNames, names...)
+}
@@ -0,0 +1,98 @@
+package application_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/stretchr/testify/assert"
+)
+
+const archiveLogData = `127.0.0.1 - - [12/Dec/2021:15:04:05 +0000] "GET /index.html HTTP/1.1" 200 1024 "-" "Mozilla/5.0"
+192.168.1.1 - - [13/Dec/2021:15:04:05 +0000] "GET /home HTTP/1.1" 200 512 "-" "Mozilla/5.0"
+`
+
+func TestLogAnalyzer_TarGzArchive(t *testing.T) {
+	var buf bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&buf)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	members := map[string]string{
+		"nginx/access.log":    archiveLogData,
+		"nginx/access.log.1":  archiveLogData,
+		"nginx/empty-dir/":    "",
+		"nginx/error.log.txt": "",
+	}
+
+	for _, name := range []string{"nginx/access.log", "nginx/access.log.1", "nginx/empty-dir/", "nginx/error.log.txt"} {
+		header := &tar.Header{Name: name, Mode: 0o600, Size: int64(len(members[name])), Typeflag: tar.TypeReg}
+		if strings.HasSuffix(name, "/") {
+			header.Typeflag = tar.TypeDir
+		}
+
+		assert.NoError(t, tarWriter.WriteHeader(header), "Failed to write tar header.")
+		_, err := tarWriter.Write([]byte(members[name]))
+		assert.NoError(t, err, "Failed to write tar member.")
+	}
+
+	assert.NoError(t, tarWriter.Close(), "Failed to close tar writer.")
+	assert.NoError(t, gzipWriter.Close(), "Failed to close gzip writer.")
+
+	archive := filepath.Join(t.TempDir(), "bundle.tar.gz")
+	assert.NoError(t, os.WriteFile(archive, buf.Bytes(), 0o600), "Failed to create archive.")
+
+	analyzer := application.NewLogAnalyzer([]string{archive}, &application.CombinedParser{})
+	err := analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", "")
+	assert.NoError(t, err, "Expected no error, but got one.")
+
+	assert.Equal(t, 4, analyzer.Metrics.TotalRequests, "TotalRequests mismatch.")
+	assert.Equal(t, []string{
+		archive + "/nginx/access.log",
+		archive + "/nginx/access.log.1",
+		archive + "/nginx/error.log.txt",
+	}, analyzer.Metrics.FileNames, "Archive members should replace the archive in FileNames.")
+}
+
+func TestLogAnalyzer_ZipArchive(t *testing.T) {
+	var buf bytes.Buffer
+
+	zipWriter := zip.NewWriter(&buf)
+
+	member, err := zipWriter.Create("logs/access.log")
+	assert.NoError(t, err, "Failed to create zip member.")
+
+	_, err = member.Write([]byte(archiveLogData))
+	assert.NoError(t, err, "Failed to write zip member.")
+	assert.NoError(t, zipWriter.Close(), "Failed to close zip writer.")
+
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "support-bundle.zip")
+	assert.NoError(t, os.WriteFile(archive, buf.Bytes(), 0o600), "Failed to create archive.")
+
+	analyzer := application.NewLogAnalyzer([]string{dir}, &application.CombinedParser{})
+	err = analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", "")
+	assert.NoError(t, err, "Expected no error, but got one.")
+
+	assert.Equal(t, 2, analyzer.Metrics.TotalRequests, "TotalRequests mismatch.")
+	assert.Equal(t, []string{dir, archive + "/logs/access.log"}, analyzer.Metrics.FileNames, "FileNames mismatch.")
+}
+
+func TestLogAnalyzer_Stdin(t *testing.T) {
+	analyzer := application.NewLogAnalyzer([]string{application.StdinPath}, nil)
+	analyzer.Stdin = strings.NewReader(archiveLogData)
+
+	err := analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", "")
+	assert.NoError(t, err, "Expected no error, but got one.")
+
+	assert.Equal(t, 2, analyzer.Metrics.TotalRequests, "TotalRequests mismatch.")
+	assert.Equal(t, []string{"stdin"}, analyzer.Metrics.FileNames, "FileNames mismatch.")
+	assert.Equal(t, "combined", analyzer.Metrics.SourceFormats["stdin"], "Format of stdin should be detected.")
+}
@@ -0,0 +1,287 @@
+package application
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+// defaultCloudFrontFields is the field list of CloudFront standard logs, used until a #Fields header is seen.
+var defaultCloudFrontFields = strings.Fields(`date time x-edge-location sc-bytes c-ip cs-method cs(Host) cs-uri-stem
+	sc-status cs(Referer) cs(User-Agent) cs-uri-query cs(Cookie) x-edge-result-type x-edge-request-id x-host-header
+	cs-protocol cs-bytes time-taken x-forwarded-for ssl-protocol ssl-cipher x-edge-response-result-type
+	cs-protocol-version fle-status fle-encrypted-fields c-port time-to-first-byte x-edge-detailed-result-type
+	sc-content-type sc-content-len sc-range-start sc-range-end`)
+
+// elbFieldCount is the number of fields parseELBFields reads, from the timestamp to the user agent.
+const elbFieldCount = 13
+
+// albRequestTypes are the values of the first field of an ALB access log entry.
+var albRequestTypes = map[string]struct{}{
+	"http": {}, "https": {}, "h2": {}, "grpcs": {}, "ws": {}, "wss": {},
+}
+
+// ALBParser parses AWS Application Load Balancer access logs.
+type ALBParser struct{}
+
+// ParseLogLine implements domain.LogParser.
+func (p *ALBParser) ParseLogLine(line string) (domain.LogRecord, error) {
+	fields, err := splitQuotedFields(line)
+	if err != nil || len(fields) < elbFieldCount+1 {
+		return domain.LogRecord{}, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	if _, ok := albRequestTypes[fields[0]]; !ok {
+		return domain.LogRecord{}, fmt.Errorf("unknown ALB request type %q", fields[0])
+	}
+
+	log, err := parseELBFields(fields[1:], "target")
+	if err != nil {
+		return log, err
+	}
+
+	log.Extra["type"] = fields[0]
+
+	return log, nil
+}
+
+// ELBParser parses AWS Classic Load Balancer access logs.
+type ELBParser struct{}
+
+// ParseLogLine implements domain.LogParser.
+func (p *ELBParser) ParseLogLine(line string) (domain.LogRecord, error) {
+	fields, err := splitQuotedFields(line)
+	if err != nil || len(fields) < elbFieldCount {
+		return domain.LogRecord{}, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	return parseELBFields(fields, "backend")
+}
+
+// parseELBFields maps the fields shared by ALB and Classic ELB entries, starting with the timestamp:
+// time elb client:port target:port request_processing_time target_processing_time
+// response_processing_time elb_status_code target_status_code received_bytes sent_bytes "request" "user_agent".
+func parseELBFields(fields []string, target string) (domain.LogRecord, error) {
+	var log domain.LogRecord
+
+	timestamp, err := time.Parse(time.RFC3339Nano, fields[0])
+	if err != nil {
+		return log, fmt.Errorf("failed to parse time: %v", err)
+	}
+
+	log.Timestamp = timestamp.UTC()
+	log.IP = hostWithoutPort(fields[2])
+	log.Extra = map[string]string{"elb": fields[1], target: fields[3], target + "_status_code": fields[8]}
+
+	names := []string{"request_processing_time", target + "_processing_time", "response_processing_time"}
+	for i, name := range names {
+		// A value of -1 means the load balancer could not dispatch the request.
+		if seconds, err := strconv.ParseFloat(fields[4+i], 64); err == nil && seconds >= 0 {
+			if log.Timings == nil {
+				log.Timings = make(map[string]float64, len(names))
+			}
+
+			log.Timings[name] = seconds
+		}
+	}
+
+	// The request took the three processing times together; the target time is spent upstream.
+	if len(log.Timings) == len(names) {
+		for _, seconds := range log.Timings {
+			log.RequestTime += seconds
+		}
+
+		log.HasRequestTime = true
+		log.UpstreamTime, log.HasUpstreamTime = log.Timings[names[1]], true
+	}
+
+	if fields[7] != "-" {
+		if log.StatusCode, err = strconv.Atoi(fields[7]); err != nil {
+			return log, fmt.Errorf("failed to parse status code: %v", err)
+		}
+	}
+
+	if log.ResponseSize, err = parseSize(fields[10]); err != nil {
+		return log, fmt.Errorf("failed to parse response size: %v", err)
+	}
+
+	if parts := strings.Fields(fields[11]); len(parts) == 3 {
+		log.Method, log.Protocol = parts[0], parts[2]
+		log.URL = requestPath(parts[1], log.Extra)
+	}
+
+	log.UserAgent = fields[12]
+
+	return log, nil
+}
+
+// CloudFrontParser parses tab-separated CloudFront standard logs in the W3C extended format.
+// The field order is taken from the #Fields header of each source.
+type CloudFrontParser struct {
+	fields []string
+}
+
+// Clone implements domain.StatefulParser.
+func (p *CloudFrontParser) Clone() domain.LogParser {
+	return &CloudFrontParser{}
+}
+
+// ParseLogLine implements domain.LogParser.
+func (p *CloudFrontParser) ParseLogLine(line string) (domain.LogRecord, error) {
+	if strings.HasPrefix(line, "#") {
+		if header, ok := strings.CutPrefix(line, "#Fields:"); ok {
+			p.fields = strings.Fields(header)
+		}
+
+		return domain.LogRecord{}, domain.ErrSkipLine
+	}
+
+	fieldNames := p.fields
+	if fieldNames == nil {
+		fieldNames = defaultCloudFrontFields
+	}
+
+	values := strings.Split(line, "\t")
+	if len(values) != len(fieldNames) {
+		return domain.LogRecord{}, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	entry := make(map[string]string, len(values))
+	for i, name := range fieldNames {
+		entry[name] = values[i]
+	}
+
+	return parseCloudFrontEntry(entry)
+}
+
+// parseCloudFrontEntry maps the named W3C fields of a CloudFront entry to a log record.
+func parseCloudFrontEntry(entry map[string]string) (domain.LogRecord, error) {
+	var log domain.LogRecord
+
+	timestamp, err := time.Parse("2006-01-02 15:04:05", entry["date"]+" "+entry["time"])
+	if err != nil {
+		return log, fmt.Errorf("failed to parse time: %v", err)
+	}
+
+	log.Timestamp = timestamp.UTC()
+
+	if log.StatusCode, err = strconv.Atoi(entry["sc-status"]); err != nil {
+		return log, fmt.Errorf("failed to parse status code: %v", err)
+	}
+
+	if log.ResponseSize, err = parseSize(entry["sc-bytes"]); err != nil {
+		return log, fmt.Errorf("failed to parse response size: %v", err)
+	}
+
+	log.IP = entry["c-ip"]
+	log.Method = entry["cs-method"]
+	log.URL = entry["cs-uri-stem"]
+	log.Protocol = entry["cs-protocol-version"]
+	log.Referer = entry["cs(Referer)"]
+	log.UserAgent = unescapeW3C(entry["cs(User-Agent)"])
+
+	if query := entry["cs-uri-query"]; query != "" && query != "-" {
+		log.URL += "?" + query
+	}
+
+	timings := map[string]string{"time_taken": entry["time-taken"], "time_to_first_byte": entry["time-to-first-byte"]}
+	for name, value := range timings {
+		if seconds, err := strconv.ParseFloat(value, 64); err == nil {
+			if log.Timings == nil {
+				log.Timings = make(map[string]float64, len(timings))
+			}
+
+			log.Timings[name] = seconds
+		}
+	}
+
+	log.RequestTime, log.HasRequestTime = log.Timings["time_taken"]
+
+	for _, name := range []string{"x-edge-location", "x-edge-result-type", "x-host-header", "cs(Host)", "x-forwarded-for"} {
+		if value, ok := entry[name]; ok && value != "-" {
+			if log.Extra == nil {
+				log.Extra = make(map[string]string)
+			}
+
+			log.Extra[name] = value
+		}
+	}
+
+	return log, nil
+}
+
+// splitQuotedFields splits a line on spaces, keeping double-quoted fields (with escaped quotes) together.
+func splitQuotedFields(line string) ([]string, error) {
+	var fields []string
+
+	for i := 0; i < len(line); {
+		switch {
+		case line[i] == ' ':
+			i++
+		case line[i] == '"':
+			var sb strings.Builder
+
+			i++
+			for ; i < len(line) && line[i] != '"'; i++ {
+				if line[i] == '\\' && i+1 < len(line) {
+					i++
+				}
+
+				sb.WriteByte(line[i])
+			}
+
+			if i >= len(line) {
+				return nil, fmt.Errorf("unterminated quoted field")
+			}
+
+			fields = append(fields, sb.String())
+			i++
+		default:
+			end := strings.IndexByte(line[i:], ' ')
+			if end < 0 {
+				end = len(line) - i
+			}
+
+			fields = append(fields, line[i:i+end])
+			i += end
+		}
+	}
+
+	return fields, nil
+}
+
+// hostWithoutPort strips the port from an "ip:port" pair; values without a port are returned unchanged.
+func hostWithoutPort(hostPort string) string {
+	if host, _, err := net.SplitHostPort(hostPort); err == nil {
+		return host
+	}
+
+	return hostPort
+}
+
+// requestPath turns the absolute URL logged by load balancers into a request URI and
+// stores the host in extra.
+func requestPath(rawURL string, extra map[string]string) string {
+	parsed, err := url.Parse(rawURL)
+	if err != nil || parsed.Host == "" {
+		return rawURL
+	}
+
+	extra["host"] = parsed.Host
+
+	return parsed.RequestURI()
+}
+
+// unescapeW3C decodes the percent-encoding CloudFront applies to fields such as the user agent.
+func unescapeW3C(value string) string {
+	if unescaped, err := url.PathUnescape(value); err == nil {
+		return unescaped
+	}
+
+	return value
+}
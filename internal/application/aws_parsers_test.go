@@ -0,0 +1,135 @@
+package application_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	albLogLine = `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 ` +
+		`192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 ` +
+		`"GET https://www.example.com:443/orders?id=1 HTTP/1.1" "curl/7.46.0" ECDHE-RSA-AES128-GCM-SHA256 TLSv1.2 ` +
+		`arn:aws:elasticloadbalancing:us-east-2:123456789012:targetgroup/my-targets/73e2d6bc24d8a067 ` +
+		`"Root=1-58337281-1d84f3d73c47ec4e58577259" "www.example.com" "-" 1 2018-07-02T22:22:48.364000Z ` +
+		`"forward" "-" "-" "10.0.0.1:80" "200" "-" "-"`
+	elbLogLine = `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 - -1 -1 -1 504 0 0 0 ` +
+		`"GET http://www.example.com:80/ HTTP/1.1" "curl/7.38.0" - -`
+	cloudFrontHeader = "#Fields: date time c-ip cs-method cs-uri-stem cs-uri-query sc-status sc-bytes " +
+		"cs(Referer) cs(User-Agent) time-taken cs-protocol-version"
+	cloudFrontLogLine = "2019-12-04\t21:02:31\t192.0.2.100\tGET\t/index.html\tlang=en\t200\t392\t-\t" +
+		"Mozilla/5.0%20(Windows%20NT%2010.0)\t0.001\tHTTP/2.0"
+)
+
+func TestALBParser_ParseLogLine(t *testing.T) {
+	record, err := (&application.ALBParser{}).ParseLogLine(albLogLine)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "192.168.131.39", record.IP)
+	assert.Equal(t, time.Date(2018, time.July, 2, 22, 23, 0, 186641000, time.UTC), record.Timestamp)
+	assert.Equal(t, "GET", record.Method)
+	assert.Equal(t, "/orders?id=1", record.URL)
+	assert.Equal(t, "HTTP/1.1", record.Protocol)
+	assert.Equal(t, 200, record.StatusCode)
+	assert.Equal(t, 57, record.ResponseSize)
+	assert.Equal(t, "curl/7.46.0", record.UserAgent)
+	assert.Equal(t, "www.example.com:443", record.Extra["host"])
+	assert.Equal(t, map[string]float64{
+		"request_processing_time":  0.086,
+		"target_processing_time":   0.048,
+		"response_processing_time": 0.037,
+	}, record.Timings)
+
+	latency, ok := record.Latency()
+	assert.True(t, ok)
+	assert.InDelta(t, 0.171, latency, 1e-9, "Request time should add up the processing times.")
+	assert.InDelta(t, 0.048, record.UpstreamTime, 1e-9)
+
+	_, err = (&application.ALBParser{}).ParseLogLine(elbLogLine)
+	assert.Error(t, err, "Classic ELB line should not parse as ALB.")
+}
+
+func TestELBParser_ParseLogLine(t *testing.T) {
+	record, err := (&application.ELBParser{}).ParseLogLine(elbLogLine)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "192.168.131.39", record.IP)
+	assert.Equal(t, 504, record.StatusCode)
+	assert.Equal(t, "/", record.URL)
+	assert.Nil(t, record.Timings, "Timings of -1 should be skipped.")
+	assert.False(t, record.HasRequestTime, "A request that was not dispatched has no request time.")
+
+	_, err = (&application.ELBParser{}).ParseLogLine(albLogLine)
+	assert.Error(t, err, "ALB line should not parse as Classic ELB.")
+}
+
+func TestAWSParsers_TruncatedLines(t *testing.T) {
+	tests := []struct {
+		name   string
+		parser domain.LogParser
+		line   string
+	}{
+		{
+			name:   "ALB line without user agent",
+			parser: &application.ALBParser{},
+			line: `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188 ` +
+				`192.168.131.39:2817 10.0.0.1:80 0.086 0.048 0.037 200 200 0 57 "GET https://www.example.com:443/ HTTP/1.1"`,
+		},
+		{
+			name:   "ALB line without request",
+			parser: &application.ALBParser{},
+			line:   `https 2018-07-02T22:23:00.186641Z app/my-loadbalancer/50dc6c495c0c9188`,
+		},
+		{
+			name:   "ELB line without user agent",
+			parser: &application.ELBParser{},
+			line: `2015-05-13T23:39:43.945958Z my-loadbalancer 192.168.131.39:2817 - -1 -1 -1 504 0 0 0 ` +
+				`"GET http://www.example.com:80/ HTTP/1.1"`,
+		},
+		{
+			name:   "ELB line without request",
+			parser: &application.ELBParser{},
+			line:   `2015-05-13T23:39:43.945958Z my-loadbalancer`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.parser.ParseLogLine(tt.line)
+			assert.Error(t, err, "Truncated lines should be rejected.")
+		})
+	}
+}
+
+func TestCloudFrontParser_ParseLogLine(t *testing.T) {
+	parser := &application.CloudFrontParser{}
+
+	_, err := parser.ParseLogLine("#Version: 1.0")
+	assert.ErrorIs(t, err, domain.ErrSkipLine)
+
+	_, err = parser.ParseLogLine(cloudFrontHeader)
+	assert.ErrorIs(t, err, domain.ErrSkipLine)
+
+	record, err := parser.ParseLogLine(cloudFrontLogLine)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.LogRecord{
+		IP:             "192.0.2.100",
+		Timestamp:      time.Date(2019, time.December, 4, 21, 2, 31, 0, time.UTC),
+		Method:         "GET",
+		URL:            "/index.html?lang=en",
+		Protocol:       "HTTP/2.0",
+		StatusCode:     200,
+		ResponseSize:   392,
+		Referer:        "-",
+		UserAgent:      "Mozilla/5.0 (Windows NT 10.0)",
+		Timings:        map[string]float64{"time_taken": 0.001},
+		RequestTime:    0.001,
+		HasRequestTime: true,
+	}, record)
+
+	_, err = parser.Clone().ParseLogLine(cloudFrontLogLine)
+	assert.Error(t, err, "A cloned parser should not keep the #Fields header.")
+}
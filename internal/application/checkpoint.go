@@ -0,0 +1,288 @@
+package application
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+// lastLineHashBytes bounds the part of the last line that is hashed into a checkpoint.
+const lastLineHashBytes = 4 << 10
+
+// Resume continues the analysis recorded in a state: the stored metrics become the base of the
+// analyzer metrics, and local files are analyzed only from where their checkpoints end.
+// After AnalyzeLogs, the Checkpoints of the analyzer describe the files analyzed so far.
+func (a *LogAnalyzer) Resume(state *domain.State) {
+	state.Metrics.Merge(a.Metrics)
+	a.Metrics = state.Metrics
+
+	a.Checkpoints = state.Sources
+	if a.Checkpoints == nil {
+		a.Checkpoints = make(map[string]*domain.Checkpoint)
+	}
+}
+
+// processFileFromCheckpoint skips the part of the file analyzed by an earlier run, passes the
+// rest up to the last complete line to process and records the checkpoint of the file in
+// a.checkpoint. Files that cannot be resumed in the middle, compressed files and archives, are
+// analyzed again as a whole once they change, and skipped if they hold a file analyzed before.
+// If processing fails, the file keeps its previous checkpoint and the records read from it are
+// dropped, so that the next run analyzes the same bytes again.
+func (a *LogAnalyzer) processFileFromCheckpoint(file *os.File, resumable bool, process func(io.Reader) error) error {
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file %s: %w", file.Name(), err)
+	}
+
+	previous := a.previousCheckpoint(file.Name(), info)
+
+	if previous != nil && previous.Size == info.Size() && previous.ModTime.Equal(info.ModTime()) {
+		fmt.Printf("Skipping %s: unchanged since the last run\n", file.Name())
+
+		a.checkpoint = previous
+
+		return nil
+	}
+
+	if previous == nil && !resumable {
+		if analyzed := a.analyzedCopy(file.Name()); analyzed != "" {
+			fmt.Printf("Skipping %s: same content as %s analyzed in an earlier run\n", file.Name(), analyzed)
+
+			a.checkpoint, err = checkpointWhole(file, info)
+
+			return err
+		}
+	}
+
+	var offset int64
+
+	switch {
+	case previous != nil && resumable:
+		offset = resumeOffset(file, info, previous)
+	case previous != nil:
+		fmt.Printf("Warning: %s changed since the last run and is analyzed again as a whole; "+
+			"its earlier records are counted twice\n", file.Name())
+	}
+
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek file %s: %w", file.Name(), err)
+	}
+
+	var processErr error
+
+	if resumable {
+		// An unterminated last line may still be being written, so it is left for the next run.
+		end, err := completeLinesEnd(file, info.Size())
+		if err != nil {
+			return fmt.Errorf("failed to read file %s: %w", file.Name(), err)
+		}
+
+		processErr = process(io.LimitReader(file, end-offset))
+	} else {
+		processErr = process(file)
+	}
+
+	if processErr != nil {
+		// The read position is past the lines buffered but never parsed, so it cannot be checkpointed.
+		a.checkpoint = previous
+
+		if !errors.Is(processErr, errSourceSkipped) {
+			a.Metrics = domain.NewMetrics(a.Paths)
+		}
+
+		return processErr
+	}
+
+	a.checkpoint, err = newCheckpoint(file, info, resumable)
+
+	return err
+}
+
+// previousCheckpoint finds the checkpoint of the file from an earlier run. Files are matched by
+// device and inode first, so a file renamed by logrotate keeps its checkpoint under the new name.
+func (a *LogAnalyzer) previousCheckpoint(path string, info os.FileInfo) *domain.Checkpoint {
+	device, inode := fileIdentity(info)
+
+	if inode != 0 {
+		for _, checkpoint := range a.Checkpoints {
+			if checkpoint.Device == device && checkpoint.Inode == inode {
+				return checkpoint
+			}
+		}
+
+		return nil // A new file, even if it took the path of a rotated one.
+	}
+
+	return a.Checkpoints[path]
+}
+
+// analyzedCopy returns the path under which an earlier run checkpointed a plain file with the
+// same content as the compressed file, such as access.log.1 that logrotate compressed into
+// access.log.2.gz, or an empty string if there is none.
+func (a *LogAnalyzer) analyzedCopy(path string) string {
+	fingerprint, _, err := contentHash(path, fingerprintBytes)
+	if err != nil || fingerprint == "" {
+		return ""
+	}
+
+	var size int64
+
+	for analyzed, checkpoint := range a.Checkpoints {
+		if checkpoint.ContentSize == 0 || checkpoint.Fingerprint != fingerprint {
+			continue
+		}
+
+		if size == 0 {
+			if _, size, err = contentHash(path, -1); err != nil {
+				return ""
+			}
+		}
+
+		if size == checkpoint.ContentSize {
+			return analyzed
+		}
+	}
+
+	return ""
+}
+
+// resumeOffset returns where the analysis of a file has to continue: at the checkpoint offset if
+// the file still holds the analyzed bytes, or at the start if it was truncated or replaced.
+func resumeOffset(file *os.File, info os.FileInfo, checkpoint *domain.Checkpoint) int64 {
+	if info.Size() < checkpoint.Offset {
+		fmt.Printf("File %s was truncated since the last run, analyzing it from the start\n", file.Name())
+		return 0
+	}
+
+	hash, err := hashRange(file, checkpoint.LastLineStart, checkpoint.Offset)
+	if err != nil || hash != checkpoint.LastLineHash {
+		fmt.Printf("File %s was replaced since the last run, analyzing it from the start\n", file.Name())
+		return 0
+	}
+
+	fmt.Printf("Resuming %s at byte %d\n", file.Name(), checkpoint.Offset)
+
+	return checkpoint.Offset
+}
+
+// completeLinesEnd returns the end of the last complete line of the file: the position after its
+// last line break, or zero if it has none.
+func completeLinesEnd(file *os.File, size int64) (int64, error) {
+	buf := make([]byte, lastLineHashBytes)
+
+	for end := size; end > 0; {
+		start := max(end-int64(len(buf)), 0)
+
+		chunk := buf[:end-start]
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return 0, err
+		}
+
+		if i := bytes.LastIndexByte(chunk, '\n'); i >= 0 {
+			return start + int64(i) + 1, nil
+		}
+
+		end = start
+	}
+
+	return 0, nil
+}
+
+// newCheckpoint records the current read position of the file as analyzed. The checkpoints of
+// plain files also fingerprint the analyzed bytes.
+func newCheckpoint(file *os.File, info os.FileInfo, resumable bool) (*domain.Checkpoint, error) {
+	offset, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkpoint file %s: %w", file.Name(), err)
+	}
+
+	tail := make([]byte, min(offset, lastLineHashBytes))
+	if _, err := file.ReadAt(tail, offset-int64(len(tail))); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint file %s: %w", file.Name(), err)
+	}
+
+	// The last line starts after the last line break that does not terminate it.
+	lastLineStart := offset - int64(len(tail))
+	if i := bytes.LastIndexByte(bytes.TrimSuffix(tail, []byte("\n")), '\n'); i >= 0 {
+		lastLineStart += int64(i + 1)
+	}
+
+	hash, err := hashRange(file, lastLineStart, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to checkpoint file %s: %w", file.Name(), err)
+	}
+
+	device, inode := fileIdentity(info)
+
+	checkpoint := &domain.Checkpoint{
+		Device:        device,
+		Inode:         inode,
+		Size:          info.Size(),
+		ModTime:       info.ModTime(),
+		Offset:        offset,
+		LastLineStart: lastLineStart,
+		LastLineHash:  hash,
+	}
+
+	if resumable && offset > 0 {
+		checkpoint.Fingerprint, err = hashRange(file, 0, min(offset, fingerprintBytes))
+		if err != nil {
+			return nil, fmt.Errorf("failed to checkpoint file %s: %w", file.Name(), err)
+		}
+
+		checkpoint.ContentSize = offset
+	}
+
+	return checkpoint, nil
+}
+
+// checkpointWhole records a compressed file or an archive as analyzed as a whole.
+func checkpointWhole(file *os.File, info os.FileInfo) (*domain.Checkpoint, error) {
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		return nil, fmt.Errorf("failed to seek file %s: %w", file.Name(), err)
+	}
+
+	return newCheckpoint(file, info, false)
+}
+
+// hashRange returns the hex-encoded SHA-256 of the bytes of the file from start to end.
+func hashRange(file *os.File, start, end int64) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, io.NewSectionReader(file, start, end-start)); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// updateCheckpoints replaces the checkpoints of the analyzed files with the ones of this run.
+// Checkpoints of files that were renamed are moved to the new name, and the ones of files that no
+// longer exist, such as rotated files deleted by logrotate, are dropped.
+func (a *LogAnalyzer) updateCheckpoints(sources []string, checkpoints []*domain.Checkpoint) {
+	for i, checkpoint := range checkpoints {
+		if checkpoint == nil {
+			continue
+		}
+
+		for path, previous := range a.Checkpoints {
+			if checkpoint.Inode != 0 && previous.Device == checkpoint.Device && previous.Inode == checkpoint.Inode {
+				delete(a.Checkpoints, path)
+			}
+		}
+
+		a.Checkpoints[sources[i]] = checkpoint
+	}
+
+	for path := range a.Checkpoints {
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			delete(a.Checkpoints, path)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package application_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogAnalyzer_Resume(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	state := domain.NewState()
+
+	write := func(path string, flag int, line string, lines int) {
+		file, err := os.OpenFile(path, flag|os.O_WRONLY|os.O_CREATE, 0o600)
+		require.NoError(t, err)
+
+		_, err = file.WriteString(strings.Repeat(line, lines))
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+	}
+
+	analyze := func() int {
+		analyzer := application.NewLogAnalyzer([]string{dir}, &application.CombinedParser{})
+		analyzer.Resume(state)
+
+		require.NoError(t, analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", ""))
+
+		state.Sources, state.Metrics = analyzer.Checkpoints, analyzer.Metrics
+
+		return analyzer.Metrics.TotalRequests
+	}
+
+	write(path, os.O_APPEND, tailLine, 3)
+	assert.Equal(t, 3, analyze(), "First run should analyze the whole file.")
+
+	assert.Equal(t, 3, analyze(), "An unchanged file should not be analyzed again.")
+
+	write(path, os.O_APPEND, tailLine, 2)
+	assert.Equal(t, 5, analyze(), "Only the appended lines should be analyzed.")
+
+	write(path, os.O_APPEND, tailLine[:20], 1)
+	assert.Equal(t, 5, analyze(), "A line still being written should be left for the next run.")
+
+	write(path, os.O_APPEND, tailLine[20:], 1)
+	assert.Equal(t, 6, analyze(), "The completed line should be analyzed once.")
+
+	require.NoError(t, os.Rename(path, path+".1"))
+	write(path+".1", os.O_APPEND, tailLine, 1)
+	write(path, os.O_APPEND, tailLine, 2)
+	assert.Equal(t, 9, analyze(), "The rotated file should be resumed and the new one analyzed whole.")
+	assert.Contains(t, state.Sources, path+".1", "Checkpoint should move to the new name of the rotated file.")
+
+	write(path, os.O_TRUNC, tailLine, 1)
+	assert.Equal(t, 10, analyze(), "A truncated file should be analyzed from the start.")
+
+	write(path, os.O_TRUNC, strings.Replace(tailLine, "/index.html", "/about.html", 1), 2)
+	assert.Equal(t, 12, analyze(), "A file with replaced content should be analyzed from the start.")
+}
+
+func TestLogAnalyzer_ResumeCompressed(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log.gz")
+	state := domain.NewState()
+
+	// Appends a gzip member, as `gzip -c >>` does.
+	appendGzip := func(lines int) {
+		var compressed bytes.Buffer
+
+		writer := gzip.NewWriter(&compressed)
+		_, err := writer.Write([]byte(strings.Repeat(tailLine, lines)))
+		require.NoError(t, err)
+		require.NoError(t, writer.Close())
+
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
+		require.NoError(t, err)
+
+		_, err = file.Write(compressed.Bytes())
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+	}
+
+	analyze := func() (int, string) {
+		analyzer := application.NewLogAnalyzer([]string{dir}, &application.CombinedParser{})
+		analyzer.Resume(state)
+
+		output := captureStdout(t, func() {
+			require.NoError(t, analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", ""))
+		})
+
+		state.Sources, state.Metrics = analyzer.Checkpoints, analyzer.Metrics
+
+		return analyzer.Metrics.TotalRequests, output
+	}
+
+	appendGzip(2)
+	total, _ := analyze()
+	assert.Equal(t, 2, total, "First run should analyze the whole file.")
+
+	appendGzip(1)
+	total, output := analyze()
+	assert.Equal(t, 5, total, "A changed compressed file should be analyzed again as a whole.")
+	assert.Contains(t, output, "Warning: "+path+" changed since the last run")
+}
+
+func TestLogAnalyzer_ResumeAfterCompression(t *testing.T) {
+	dir := t.TempDir()
+	rotated := filepath.Join(dir, "access.log.1")
+	state := domain.NewState()
+
+	analyze := func() (int, string) {
+		analyzer := application.NewLogAnalyzer([]string{dir}, &application.CombinedParser{})
+		analyzer.Resume(state)
+
+		output := captureStdout(t, func() {
+			require.NoError(t, analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", ""))
+		})
+
+		state.Sources, state.Metrics = analyzer.Checkpoints, analyzer.Metrics
+
+		return analyzer.Metrics.TotalRequests, output
+	}
+
+	require.NoError(t, os.WriteFile(rotated, []byte(strings.Repeat(tailLine, 3)), 0o600))
+
+	total, _ := analyze()
+	assert.Equal(t, 3, total, "First run should analyze the rotated file.")
+
+	// Logrotate with delaycompress compresses access.log.1 into access.log.2.gz on the next rotation.
+	var compressed bytes.Buffer
+
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte(strings.Repeat(tailLine, 3)))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "access.log.2.gz"), compressed.Bytes(), 0o600))
+	require.NoError(t, os.Remove(rotated))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "access.log"), []byte(tailLine), 0o600))
+
+	total, output := analyze()
+	assert.Equal(t, 4, total, "The compressed copy of an analyzed file should not be counted again.")
+	assert.Contains(t, output, "same content as "+rotated)
+
+	total, _ = analyze()
+	assert.Equal(t, 4, total, "Unchanged files should be skipped.")
+}
+
+func TestLogAnalyzer_ResumeFailedFiles(t *testing.T) {
+	dir := t.TempDir()
+	broken := filepath.Join(dir, "access.log")
+	unknown := filepath.Join(dir, "app.log")
+	state := domain.NewState()
+
+	analyze := func() int {
+		analyzer := application.NewLogAnalyzer([]string{dir}, nil)
+		analyzer.Resume(state)
+
+		captureStdout(t, func() {
+			require.NoError(t, analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", ""))
+		})
+
+		state.Sources, state.Metrics = analyzer.Checkpoints, analyzer.Metrics
+
+		return analyzer.Metrics.TotalRequests
+	}
+
+	// A line longer than the scanner buffer stops the analysis after the first lines.
+	content := strings.Repeat(tailLine, 2) + strings.Repeat("x", 100<<10) + "\n" + tailLine
+	require.NoError(t, os.WriteFile(broken, []byte(content), 0o600))
+	require.NoError(t, os.WriteFile(unknown, []byte("not a log line\n"), 0o600))
+
+	assert.Equal(t, 0, analyze(), "Records of a file that failed should not be kept.")
+	assert.NotContains(t, state.Sources, broken, "A file that failed should not be checkpointed.")
+	assert.NotContains(t, state.Sources, unknown, "A file of an unknown format should not be checkpointed.")
+
+	require.NoError(t, os.WriteFile(broken, []byte(strings.Repeat(tailLine, 3)), 0o600))
+	assert.Equal(t, 3, analyze(), "The fixed file should be analyzed as a whole.")
+	assert.Contains(t, state.Sources, broken)
+
+	require.NoError(t, os.Remove(broken))
+	analyze()
+	assert.NotContains(t, state.Sources, broken, "Checkpoints of deleted files should be dropped.")
+}
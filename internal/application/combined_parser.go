@@ -0,0 +1,418 @@
+package application
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+// combinedTimeLayout is the layout of timestamps in the common and combined log formats.
+const combinedTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// monthAbbreviations are the month names of combinedTimeLayout, indexed by month number minus one.
+var monthAbbreviations = [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"}
+
+// text is a line being parsed: a string, or the bytes of a scanner buffer.
+type text interface {
+	~string | ~[]byte
+}
+
+// span is the position of a field within a line.
+type span struct {
+	start, end int
+}
+
+// combinedFields holds the positions of the text fields of a line in the combined log format.
+type combinedFields struct {
+	ip, ident, user, method, url, protocol, referer, agent span
+}
+
+// ParseLogBytes implements domain.ByteParser. The line is parsed in place; only the text fields the
+// record keeps are copied, all of them into a single string, and common values such as "GET",
+// "HTTP/1.1" and "-" are not copied at all.
+func (p *CombinedParser) ParseLogBytes(line []byte, log *domain.LogRecord) error {
+	fields, err := splitCombined(line, log)
+	if err != nil {
+		return err
+	}
+
+	kept := [...]struct {
+		field *string
+		span  span
+	}{
+		{&log.IP, fields.ip}, {&log.Ident, fields.ident}, {&log.RemoteUser, fields.user},
+		{&log.Method, fields.method}, {&log.URL, fields.url}, {&log.Protocol, fields.protocol},
+		{&log.Referer, fields.referer}, {&log.UserAgent, fields.agent},
+	}
+
+	size := 0
+
+	for i := range kept {
+		value := line[kept[i].span.start:kept[i].span.end]
+		if known, ok := wellKnownValue(value); ok {
+			*kept[i].field, kept[i].span = known, span{}
+		} else {
+			size += len(value)
+		}
+	}
+
+	var sb strings.Builder
+
+	sb.Grow(size)
+
+	for _, k := range kept {
+		sb.Write(line[k.span.start:k.span.end])
+	}
+
+	copied, offset := sb.String(), 0
+
+	for _, k := range kept {
+		if length := k.span.end - k.span.start; length > 0 {
+			*k.field, offset = copied[offset:offset+length], offset+length
+		}
+	}
+
+	log.Ident = optionalField(log.Ident)
+	log.RemoteUser = optionalField(log.RemoteUser)
+
+	return nil
+}
+
+// wellKnownValue returns common field values as constant strings, so that they need not be copied.
+func wellKnownValue(value []byte) (string, bool) {
+	switch string(value) {
+	case "":
+		return "", true
+	case "-":
+		return "-", true
+	case "GET":
+		return "GET", true
+	case "POST":
+		return "POST", true
+	case "HEAD":
+		return "HEAD", true
+	case "PUT":
+		return "PUT", true
+	case "HTTP/1.0":
+		return "HTTP/1.0", true
+	case "HTTP/1.1":
+		return "HTTP/1.1", true
+	case "HTTP/2.0":
+		return "HTTP/2.0", true
+	default:
+		return "", false
+	}
+}
+
+// parseCombined parses a line in the combined log format without regular expressions. The string
+// fields of the record are substrings of the line, so parsing itself does not allocate.
+func parseCombined(line string, log *domain.LogRecord) error {
+	fields, err := splitCombined(line, log)
+	if err != nil {
+		return err
+	}
+
+	log.IP = line[fields.ip.start:fields.ip.end]
+	log.Ident = optionalField(line[fields.ident.start:fields.ident.end])
+	log.RemoteUser = optionalField(line[fields.user.start:fields.user.end])
+	log.Method = line[fields.method.start:fields.method.end]
+	log.URL = line[fields.url.start:fields.url.end]
+	log.Protocol = line[fields.protocol.start:fields.protocol.end]
+	log.Referer = line[fields.referer.start:fields.referer.end]
+	log.UserAgent = line[fields.agent.start:fields.agent.end]
+
+	return nil
+}
+
+// splitCombined locates the fields of a line in the combined log format, stores its timestamp,
+// status code and response size in the record and returns the positions of the text fields.
+//
+// It accepts the same lines as combinedPattern, which allows backslash-escaped quotes inside the
+// quoted fields, as Apache writes them. Escape sequences are kept as they are.
+func splitCombined[T text](line T, log *domain.LogRecord) (combinedFields, error) {
+	var fields combinedFields
+
+	// The line ends with `"request" status size "referer" "agent"`. The quotes are located from
+	// the right, because the request of a malformed line may contain quotes that are not escaped.
+	agentEnd := len(line) - 1
+	if agentEnd < 0 || line[agentEnd] != '"' || isEscaped(line, agentEnd) {
+		return fields, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	agentStart := lastQuote(line, agentEnd)
+	if agentStart < 2 || line[agentStart-1] != ' ' || line[agentStart-2] != '"' || isEscaped(line, agentStart-2) {
+		return fields, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	refererEnd := agentStart - 2
+
+	refererStart := lastQuote(line, refererEnd)
+	if refererStart < 1 || line[refererStart-1] != ' ' {
+		return fields, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	requestEnd := lastQuote(line, refererStart)
+	if requestEnd < 0 {
+		return fields, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	status, size, ok := splitStatusAndSize(line, span{requestEnd + 1, refererStart - 1})
+	if !ok {
+		return fields, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	timestamp, ok := splitCombinedHead(line, requestEnd, &fields)
+	if !ok {
+		return fields, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	var err error
+
+	if log.Timestamp, err = parseCombinedTime(line[timestamp.start:timestamp.end]); err != nil {
+		return fields, fmt.Errorf("failed to parse time: %v", err)
+	}
+
+	if log.StatusCode, ok = parseDigits(line[status.start:status.end]); !ok {
+		return fields, fmt.Errorf("failed to parse status code: %s", line[status.start:status.end])
+	}
+
+	if log.ResponseSize, ok = parseDigits(line[size.start:size.end]); !ok && !isDash(line[size.start:size.end]) {
+		return fields, fmt.Errorf("failed to parse response size: %s", line[size.start:size.end])
+	}
+
+	fields.referer = span{refererStart + 1, refererEnd}
+	fields.agent = span{agentStart + 1, agentEnd}
+
+	return fields, nil
+}
+
+// splitCombinedHead splits `ip ident user [time] "method url protocol`, the part of the line before
+// the closing quote of the request at headEnd, into the fields and returns the position of the timestamp.
+func splitCombinedHead[T text](line T, headEnd int, fields *combinedFields) (timestamp span, ok bool) {
+	pos := 0
+
+	if fields.ip, pos, ok = cutToken(line, pos, headEnd); !ok {
+		return span{}, false
+	}
+
+	if fields.ident, pos, ok = cutToken(line, pos, headEnd); !ok {
+		return span{}, false
+	}
+
+	if fields.user, pos, ok = cutToken(line, pos, headEnd); !ok {
+		return span{}, false
+	}
+
+	closing := indexByte(line, ']', pos, headEnd)
+	if pos >= headEnd || line[pos] != '[' || closing < pos+2 || closing+3 > headEnd ||
+		line[closing+1] != ' ' || line[closing+2] != '"' {
+		return span{}, false
+	}
+
+	timestamp, pos = span{pos + 1, closing}, closing+3
+
+	if fields.method, pos, ok = cutToken(line, pos, headEnd); !ok {
+		return span{}, false
+	}
+
+	if fields.url, pos, ok = cutToken(line, pos, headEnd); !ok {
+		return span{}, false
+	}
+
+	fields.protocol = span{pos, headEnd}
+
+	return timestamp, isToken(line[pos:headEnd])
+}
+
+// splitStatusAndSize splits the ` status size` part between the request and the referer.
+func splitStatusAndSize[T text](line T, part span) (status, size span, ok bool) {
+	if part.start >= part.end || line[part.start] != ' ' {
+		return span{}, span{}, false
+	}
+
+	separator := indexByte(line, ' ', part.start+1, part.end)
+	if separator < 0 {
+		return span{}, span{}, false
+	}
+
+	status, size = span{part.start + 1, separator}, span{separator + 1, part.end}
+
+	if !isDigits(line[status.start:status.end]) ||
+		!isDash(line[size.start:size.end]) && !isDigits(line[size.start:size.end]) {
+		return span{}, span{}, false
+	}
+
+	return status, size, true
+}
+
+// parseCombinedTime parses a timestamp in combinedTimeLayout and returns it in UTC. Timestamps in
+// the canonical form are decoded by hand; anything else is left to time.Parse.
+func parseCombinedTime[T text](value T) (time.Time, error) {
+	if timestamp, ok := decodeCombinedTime(value); ok {
+		return timestamp, nil
+	}
+
+	timestamp, err := time.Parse(combinedTimeLayout, string(value))
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return timestamp.UTC(), nil
+}
+
+// decodeCombinedTime decodes a valid timestamp of the form 10/Oct/2000:13:55:36 -0700.
+func decodeCombinedTime[T text](value T) (time.Time, bool) {
+	if len(value) != len(combinedTimeLayout) || value[2] != '/' || value[6] != '/' || value[11] != ':' ||
+		value[14] != ':' || value[17] != ':' || value[20] != ' ' || (value[21] != '+' && value[21] != '-') {
+		return time.Time{}, false
+	}
+
+	month := 0
+
+	for i, name := range monthAbbreviations {
+		if value[3] == name[0] && value[4] == name[1] && value[5] == name[2] {
+			month = i + 1
+			break
+		}
+	}
+
+	day, dayOK := twoDigits(value[0:2])
+	yearHigh, yearHighOK := twoDigits(value[7:9])
+	yearLow, yearLowOK := twoDigits(value[9:11])
+	hour, hourOK := twoDigits(value[12:14])
+	minute, minuteOK := twoDigits(value[15:17])
+	second, secondOK := twoDigits(value[18:20])
+	zoneHour, zoneHourOK := twoDigits(value[22:24])
+	zoneMinute, zoneMinuteOK := twoDigits(value[24:26])
+
+	if month == 0 || !dayOK || !yearHighOK || !yearLowOK || !hourOK || !minuteOK || !secondOK ||
+		!zoneHourOK || !zoneMinuteOK || hour > 23 || minute > 59 || second > 59 || zoneHour > 23 || zoneMinute > 59 {
+		return time.Time{}, false
+	}
+
+	year := yearHigh*100 + yearLow
+
+	// time.Date normalizes days past the end of the month, time.Parse rejects them.
+	if day < 1 || day > time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day() {
+		return time.Time{}, false
+	}
+
+	offset := time.Duration(zoneHour)*time.Hour + time.Duration(zoneMinute)*time.Minute
+	if value[21] == '-' {
+		offset = -offset
+	}
+
+	return time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC).Add(-offset), true
+}
+
+// cutToken cuts the non-empty run of non-whitespace characters between pos and the next space
+// before end, returning its position and the position after the space.
+func cutToken[T text](line T, pos, end int) (token span, next int, ok bool) {
+	separator := indexByte(line, ' ', pos, end)
+	if separator < 0 || !isToken(line[pos:separator]) {
+		return span{}, 0, false
+	}
+
+	return span{pos, separator}, separator + 1, true
+}
+
+// indexByte returns the index of the first c in line[start:end], or -1.
+func indexByte[T text](line T, c byte, start, end int) int {
+	for i := start; i < end; i++ {
+		if line[i] == c {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// isToken reports whether s is non-empty and matches \S+.
+func isToken[T text](s T) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case ' ', '\t', '\n', '\f', '\r':
+			return false
+		}
+	}
+
+	return true
+}
+
+// isDigits reports whether s is non-empty and consists of ASCII digits only.
+func isDigits[T text](s T) bool {
+	if len(s) == 0 {
+		return false
+	}
+
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+// isDash reports whether s is "-", the placeholder of a missing value.
+func isDash[T text](s T) bool {
+	return len(s) == 1 && s[0] == '-'
+}
+
+// parseDigits decodes a non-negative decimal number, failing on anything but digits and on overflow.
+func parseDigits[T text](s T) (int, bool) {
+	if !isDigits(s) {
+		return 0, false
+	}
+
+	n := 0
+
+	for i := 0; i < len(s); i++ {
+		digit := int(s[i] - '0')
+		if n > (math.MaxInt-digit)/10 {
+			return 0, false
+		}
+
+		n = n*10 + digit
+	}
+
+	return n, true
+}
+
+// twoDigits decodes a two-digit decimal number.
+func twoDigits[T text](s T) (int, bool) {
+	if !isDigits(s) {
+		return 0, false
+	}
+
+	return int(s[0]-'0')*10 + int(s[1]-'0'), true
+}
+
+// lastQuote returns the index of the last unescaped double quote before end, or -1.
+func lastQuote[T text](line T, end int) int {
+	for i := end - 1; i >= 0; i-- {
+		if line[i] == '"' && !isEscaped(line, i) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// isEscaped reports whether the character at index i is preceded by an odd number of backslashes.
+func isEscaped[T text](line T, i int) bool {
+	backslashes := 0
+	for i > 0 && line[i-1] == '\\' {
+		backslashes++
+		i--
+	}
+
+	return backslashes%2 == 1
+}
@@ -0,0 +1,144 @@
+package application_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const benchmarkCombinedLine = `93.180.71.3 - - [17/May/2015:08:05:32 +0000] "GET /downloads/product_1 HTTP/1.1" 304 0 "-" ` +
+	`"Debian APT-HTTP/1.3 (0.8.16~exp12ubuntu10.21)"`
+
+func TestCombinedParser_ParseLogBytes(t *testing.T) {
+	tests := []struct {
+		name      string
+		logLine   string
+		expected  domain.LogRecord
+		expectErr bool
+	}{
+		{
+			name:    "Escaped quotes in request and user agent",
+			logLine: `127.0.0.1 - bob [12/Dec/2021:19:01:02 +0300] "GET /search?q=\"x\" HTTP/1.1" 200 10 "-" "Agent \"quoted\" \\"`,
+			expected: domain.LogRecord{
+				IP:           "127.0.0.1",
+				RemoteUser:   "bob",
+				Timestamp:    time.Date(2021, time.December, 12, 16, 1, 2, 0, time.UTC),
+				Method:       "GET",
+				URL:          `/search?q=\"x\"`,
+				Protocol:     "HTTP/1.1",
+				StatusCode:   200,
+				ResponseSize: 10,
+				Referer:      "-",
+				UserAgent:    `Agent \"quoted\" \\`,
+			},
+		},
+		{
+			name:    "Timestamp in non-canonical form",
+			logLine: `127.0.0.1 - - [02/jan/2021:7:01:02 -0130] "GET / HTTP/1.1" 200 - "-" "-"`,
+			expected: domain.LogRecord{
+				IP:         "127.0.0.1",
+				Timestamp:  time.Date(2021, time.January, 2, 8, 31, 2, 0, time.UTC),
+				Method:     "GET",
+				URL:        "/",
+				Protocol:   "HTTP/1.1",
+				StatusCode: 200,
+				Referer:    "-",
+				UserAgent:  "-",
+			},
+		},
+		{
+			name:      "Escaped closing quote of user agent",
+			logLine:   `127.0.0.1 - - [12/Dec/2021:19:01:02 +0000] "GET / HTTP/1.1" 200 10 "-" "Agent\"`,
+			expectErr: true,
+		},
+		{
+			name:      "Invalid day of month",
+			logLine:   `127.0.0.1 - - [31/Feb/2021:19:01:02 +0000] "GET / HTTP/1.1" 200 10 "-" "-"`,
+			expectErr: true,
+		},
+		{
+			name:      "Request with two fields",
+			logLine:   `127.0.0.1 - - [12/Dec/2021:19:01:02 +0000] "GET /" 200 10 "-" "-"`,
+			expectErr: true,
+		},
+	}
+
+	parser := &application.CombinedParser{}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var result domain.LogRecord
+
+			err := parser.ParseLogBytes([]byte(tt.logLine), &result)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+// FuzzCombinedParser checks that the hand-written parser agrees with the regexp implementation,
+// for both strings and bytes.
+func FuzzCombinedParser(f *testing.F) {
+	f.Add(benchmarkCombinedLine)
+	f.Add(`127.0.0.1 ident alice [12/Dec/2021:19:01:02 -0700] "POST /submit HTTP/1.1" 404 - "http://a/b" "-"`)
+	f.Add(`127.0.0.1 - - [12/Dec/2021:19:01:02 +0000] "GET /a"b HTTP/1.1" 200 1 "" ""`)
+	f.Add(`127.0.0.1 - - [2021-12-12 19:01:02] "GET / HTTP/1.1" 200 1 "-" "-"`)
+	f.Add(`127.0.0.1 - - [12/Dec/2021:19:01:02 +0000] "GET / HTTP/1.1" 200 1 "-" "a \"b\""`)
+	f.Add(`127.0.0.1 - - [12/Dec/2021:19:01:02 +0000] "GET /?q=\"x\" HTTP/1.1\\" 200 1 "\\" "a\\\"\\"`)
+	f.Add(`127.0.0.1 - - [12/Dec/2021:19:01:02 +0000] "GET / HTTP/1.1\" 200 1 "-" "-"`)
+
+	parser := &application.CombinedParser{}
+
+	f.Fuzz(func(t *testing.T, line string) {
+		var record domain.LogRecord
+
+		err := parser.ParseLogBytes([]byte(line), &record)
+		fromString, stringErr := parser.ParseLogLine(line)
+
+		expected, expectedErr := application.ParseLogLine(line)
+		if expectedErr != nil {
+			assert.Error(t, err)
+			assert.Error(t, stringErr)
+
+			return
+		}
+
+		require.NoError(t, err)
+		require.NoError(t, stringErr)
+		assert.Equal(t, expected, record)
+		assert.Equal(t, expected, fromString)
+	})
+}
+
+func BenchmarkCombinedParser(b *testing.B) {
+	parser := &application.CombinedParser{}
+	line := []byte(benchmarkCombinedLine)
+
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		var record domain.LogRecord
+		if err := parser.ParseLogBytes(line, &record); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkParseLogLine_Regexp(b *testing.B) {
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := application.ParseLogLine(benchmarkCombinedLine); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,69 @@
+package application
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/ulikunitz/xz"
+)
+
+var (
+	gzipMagic  = []byte{0x1f, 0x8b}
+	bzip2Magic = []byte("BZh")
+	zstdMagic  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	xzMagic    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+)
+
+// isCompressed reports whether data starts with the magic bytes of a supported compression format.
+func isCompressed(data []byte) bool {
+	for _, magic := range [][]byte{gzipMagic, bzip2Magic, zstdMagic, xzMagic} {
+		if bytes.HasPrefix(data, magic) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// decompress sniffs the magic bytes at the start of the stream and wraps it in the matching
+// decompressor. Uncompressed streams are returned as is. Data is decompressed while it is read,
+// so nothing is written to disk.
+func decompress(reader io.Reader) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(reader)
+
+	// A short stream simply yields fewer bytes, which are compared below.
+	magic, _ := buffered.Peek(len(xzMagic))
+
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		gzipReader, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open gzip stream: %w", err)
+		}
+
+		return gzipReader, nil
+	case bytes.HasPrefix(magic, bzip2Magic):
+		return io.NopCloser(bzip2.NewReader(buffered)), nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zstdReader, err := zstd.NewReader(buffered, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, fmt.Errorf("failed to open zstd stream: %w", err)
+		}
+
+		return zstdReader.IOReadCloser(), nil
+	case bytes.HasPrefix(magic, xzMagic):
+		xzReader, err := xz.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open xz stream: %w", err)
+		}
+
+		return io.NopCloser(xzReader), nil
+	default:
+		return io.NopCloser(buffered), nil
+	}
+}
@@ -0,0 +1,71 @@
+package application_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/ulikunitz/xz"
+)
+
+const compressedLogLine = `127.0.0.1 - - [12/Dec/2021:15:04:05 +0000] "GET /index.html HTTP/1.1" 200 1024 "-" "Mozilla/5.0"` + "\n"
+
+// bzip2LogLine is compressedLogLine compressed with bzip2, which has no encoder in the standard library.
+const bzip2LogLine = "425a683931415926535965090cfb00001a5f804010500bf69006c2440a2e67845020005454f53ca320d00f50f5007a9ea" +
+	"1134353d464034c83d20052d7e6bb2b748cb86b049e0a23be802f7444c9c61050b060baa2041134913df34f4384a57088da62498400d036" +
+	"24d79f78e8dac582b02fd5b7e2ee48a70a120ca1219f60"
+
+func compressWith(t *testing.T, newWriter func(io.Writer) (io.WriteCloser, error)) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+
+	writer, err := newWriter(&buf)
+	assert.NoError(t, err, "Failed to create compressor.")
+
+	_, err = writer.Write([]byte(compressedLogLine))
+	assert.NoError(t, err, "Failed to compress data.")
+	assert.NoError(t, writer.Close(), "Failed to close compressor.")
+
+	return buf.Bytes()
+}
+
+func TestLogAnalyzer_CompressedFiles(t *testing.T) {
+	bzip2Data, err := hex.DecodeString(bzip2LogLine)
+	assert.NoError(t, err, "Failed to decode bzip2 fixture.")
+
+	files := map[string][]byte{
+		"access.log":     []byte(compressedLogLine),
+		"access.log.bz2": bzip2Data,
+		"access.log.1.gz": compressWith(t, func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		}),
+		"access.log.2.zst": compressWith(t, func(w io.Writer) (io.WriteCloser, error) {
+			return zstd.NewWriter(w)
+		}),
+		"access.log.3.xz": compressWith(t, func(w io.Writer) (io.WriteCloser, error) {
+			return xz.NewWriter(w)
+		}),
+	}
+
+	dir := t.TempDir()
+
+	for name, data := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600), "Failed to create %s.", name)
+	}
+
+	analyzer := application.NewLogAnalyzer([]string{dir}, &application.CombinedParser{})
+	err = analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", "")
+	assert.NoError(t, err, "Expected no error, but got one.")
+
+	assert.Equal(t, len(files), analyzer.Metrics.TotalRequests, "Every compressed file should yield one request.")
+	assert.Equal(t, len(files), analyzer.Metrics.ResourceStats["/index.html"].Requests, "Resources mismatch.")
+}
@@ -0,0 +1,4 @@
+package application
+
+// SignV4 exposes signV4 to the tests of the package.
+var SignV4 = signV4
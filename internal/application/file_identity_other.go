@@ -0,0 +1,11 @@
+//go:build !unix
+
+package application
+
+import "os"
+
+// fileIdentity returns zeros, as files have no device and inode numbers on this platform.
+// Checkpoints are then matched by path only, so renamed files are analyzed again.
+func fileIdentity(_ os.FileInfo) (device, inode uint64) {
+	return 0, 0
+}
@@ -0,0 +1,18 @@
+//go:build unix
+
+package application
+
+import (
+	"os"
+	"syscall"
+)
+
+// fileIdentity returns the device and inode of a file, which stay the same when the file is renamed.
+func fileIdentity(info os.FileInfo) (device, inode uint64) {
+	stat, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return 0, 0
+	}
+
+	return uint64(stat.Dev), uint64(stat.Ino) //nolint:unconvert // The field types differ between platforms.
+}
@@ -2,13 +2,13 @@ package application
 
 import (
 	"fmt"
-	"net/http"
+	"io"
 	"net/url"
 	"os"
 	"time"
 )
 
-// processFile processes a single file.
+// processFile processes a single file, which may be compressed or a tar or zip archive.
 func (a *LogAnalyzer) processFile(filePath string, from, to time.Time, filterField, filterValue string) error {
 	file, err := os.Open(filePath)
 	if err != nil {
@@ -16,10 +16,34 @@ func (a *LogAnalyzer) processFile(filePath string, from, to time.Time, filterFie
 	}
 	defer file.Close()
 
-	return a.processLogs(file, from, to, filterField, filterValue)
+	isZip := isZipFile(filePath)
+
+	process := func(reader io.Reader) error {
+		if isZip {
+			return a.processZip(filePath, from, to, filterField, filterValue)
+		}
+
+		return a.processStream(reader, filePath, from, to, filterField, filterValue)
+	}
+
+	if a.Checkpoints != nil {
+		return a.processFileFromCheckpoint(file, isPlainFile(file), process)
+	}
+
+	return process(file)
+}
+
+// processStdin processes logs piped into the standard input.
+func (a *LogAnalyzer) processStdin(from, to time.Time, filterField, filterValue string) error {
+	fmt.Println("Processing standard input")
+
+	a.replaceFileName(StdinPath, []string{stdinSource})
+
+	return a.processStream(a.Stdin, stdinSource, from, to, filterField, filterValue)
 }
 
 // processURL processes logs directly from a URL without loading into memory.
+// Interrupted downloads are resumed as configured by a.HTTP.
 func (a *LogAnalyzer) processURL(rawURL string, from, to time.Time, filterField, filterValue string) error {
 	// Parse and validate the URL.
 	parsedURL, err := url.Parse(rawURL)
@@ -31,16 +55,11 @@ func (a *LogAnalyzer) processURL(rawURL string, from, to time.Time, filterField,
 		return fmt.Errorf("unsupported URL scheme for %s", rawURL)
 	}
 
-	// Perform the HTTP GET request.
-	resp, err := http.Get(parsedURL.String())
+	body, err := a.HTTP.openURL(parsedURL.String())
 	if err != nil {
-		return fmt.Errorf("failed to fetch file from URL %s: %w", rawURL, err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected HTTP status for URL %s: %s", rawURL, resp.Status)
+		return err
 	}
+	defer body.Close()
 
-	return a.processLogs(resp.Body, from, to, filterField, filterValue)
+	return a.processStream(body, rawURL, from, to, filterField, filterValue)
 }
@@ -4,12 +4,13 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/abakunov/log-analyzer/internal/domain"
 )
 
-var unknownFieldWarned = false // Tracks if the warning has been shown.
+var unknownFieldWarning sync.Once // Makes sure the warning is shown only once.
 
 // matchesFilter checks if a log record matches the filter criteria.
 func matchesFilter(logRecord *domain.LogRecord, field, value string) bool {
@@ -37,12 +38,12 @@ func matchesFilter(logRecord *domain.LogRecord, field, value string) bool {
 		return matchStringField(logRecord.Referer, value, isWildcard)
 	case "agent":
 		return matchStringField(logRecord.UserAgent, value, isWildcard)
+	case "user":
+		return matchStringField(logRecord.RemoteUser, value, isWildcard)
 	default:
-		if !unknownFieldWarned {
+		unknownFieldWarning.Do(func() {
 			fmt.Printf("Unknown filter field: %s\n", field)
-
-			unknownFieldWarned = true
-		}
+		})
 
 		return false
 	}
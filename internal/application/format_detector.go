@@ -0,0 +1,196 @@
+package application
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+const (
+	// AutoDetectFormat is the log format name that enables per-source format detection.
+	AutoDetectFormat = "auto"
+
+	// detectSampleLines is the number of leading lines used to score log formats.
+	detectSampleLines = 20
+	// detectSampleBytes bounds how much of a source is buffered for format detection.
+	detectSampleBytes = 64 * 1024
+)
+
+// ErrUnknownFormat is returned when none of the registered log formats matches a source.
+var ErrUnknownFormat = errors.New("no registered log format matches the source")
+
+// DetectFormat scores every log format of the registry, or every registered one if it is nil, against
+// the sample lines and returns the best match. The score of a format is the number of sample lines it
+// parses successfully; ties are resolved by format name so that detection is deterministic.
+func DetectFormat(sample []string, formats ParserRegistry) (string, domain.LogParser, error) {
+	var (
+		bestName   string
+		bestParser domain.LogParser
+		bestScore  int
+	)
+
+	if formats == nil {
+		formats = RegisteredParsers()
+	}
+
+	for _, name := range formats.Names() {
+		parser, err := formats.NewParser(name)
+		if err != nil {
+			continue
+		}
+
+		if score := scoreFormat(parser, sample); score > bestScore {
+			bestName, bestParser, bestScore = name, parser, score
+		}
+	}
+
+	if bestParser == nil {
+		return "", nil, ErrUnknownFormat
+	}
+
+	// Scoring may have changed the state of the parser, so hand out a fresh one.
+	if stateful, ok := bestParser.(domain.StatefulParser); ok {
+		bestParser = stateful.Clone()
+	}
+
+	return bestName, bestParser, nil
+}
+
+// scoreFormat returns the number of sample lines the parser parses. A parser that panics on a line
+// scores zero, so that it cannot take down the detection for every source.
+func scoreFormat(parser domain.LogParser, sample []string) (score int) {
+	defer func() {
+		if recover() != nil {
+			score = 0
+		}
+	}()
+
+	for _, line := range sample {
+		if _, err := parser.ParseLogLine(line); err == nil {
+			score++
+		}
+	}
+
+	return score
+}
+
+// sampleLines returns up to detectSampleLines complete lines from the start of the reader without consuming them.
+func sampleLines(reader *bufio.Reader) []string {
+	// Peek fails when the source is shorter than the sample size. The data then holds
+	// the whole source and its last line is complete.
+	data, err := reader.Peek(detectSampleBytes)
+
+	lines := bytes.Split(data, []byte("\n"))
+	if err == nil {
+		lines = lines[:len(lines)-1]
+	}
+
+	sample := make([]string, 0, detectSampleLines)
+
+	for _, line := range lines {
+		if len(sample) == detectSampleLines {
+			break
+		}
+
+		if len(bytes.TrimSpace(line)) > 0 {
+			sample = append(sample, strings.TrimSuffix(string(line), "\r"))
+		}
+	}
+
+	return sample
+}
+
+// parserFor returns the parser to use for the source, detecting the format from the buffered reader
+// when the analyzer was created without an explicit parser. It returns no parser while the reader
+// has no lines to detect the format from.
+func (a *LogAnalyzer) parserFor(source string, reader *bufio.Reader) (domain.LogParser, error) {
+	if stateful, ok := a.Parser.(domain.StatefulParser); ok {
+		return stateful.Clone(), nil
+	}
+
+	if a.Parser != nil {
+		return a.Parser, nil
+	}
+
+	sample := sampleLines(reader)
+	if len(sample) == 0 {
+		return nil, nil
+	}
+
+	name, parser, err := DetectFormat(sample, a.Formats)
+	if err != nil {
+		a.Metrics.SourceFormats[source] = "unknown (skipped)"
+		return nil, fmt.Errorf("%w: %s", err, source)
+	}
+
+	a.Metrics.SourceFormats[source] = name
+
+	return parser, nil
+}
+
+// lineSink parses the lines of a source that is read as they arrive into the analyzer metrics.
+// Without a parser, the format is detected from the first lines, which are held back until enough
+// of them arrive or flush is called.
+type lineSink struct {
+	analyzer                 *LogAnalyzer
+	source                   string
+	parser                   domain.LogParser
+	sample                   []string
+	from, to                 time.Time
+	filterField, filterValue string
+}
+
+// add processes a line of the source.
+func (s *lineSink) add(line string) {
+	if s.parser == nil && s.analyzer.Parser != nil {
+		s.parser, _ = s.analyzer.parserFor(s.source, nil)
+	}
+
+	if s.parser == nil {
+		s.sample = append(s.sample, line)
+		if len(s.sample) >= detectSampleLines {
+			s.flush()
+		}
+
+		return
+	}
+
+	s.analyzer.processLine(s.analyzer.Metrics, s.parser, line, s.from, s.to, s.filterField, s.filterValue)
+}
+
+// flush detects the format from the lines held back so far and processes them.
+func (s *lineSink) flush() {
+	if s.parser != nil || len(s.sample) == 0 {
+		return
+	}
+
+	sample := s.sample
+	s.sample = nil
+
+	name, parser, err := DetectFormat(sample, s.analyzer.Formats)
+	if err != nil {
+		fmt.Printf("Error detecting the format of %s: %v\n", s.source, err)
+		return
+	}
+
+	s.analyzer.Metrics.SourceFormats[s.source] = name
+	s.parser = parser
+
+	for _, line := range sample {
+		s.analyzer.processLine(s.analyzer.Metrics, s.parser, line, s.from, s.to, s.filterField, s.filterValue)
+	}
+}
+
+// formatNames returns the names of the log formats tried by format detection.
+func (a *LogAnalyzer) formatNames() []string {
+	if a.Formats == nil {
+		return ParserNames()
+	}
+
+	return a.Formats.Names()
+}
@@ -0,0 +1,138 @@
+package application_test
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDetectFormat(t *testing.T) {
+	testCases := []struct {
+		name      string
+		sample    []string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name: "Combined format",
+			sample: []string{
+				`127.0.0.1 - - [12/Dec/2021:15:04:05 +0000] "GET /index.html HTTP/1.1" 200 1024 "-" "Mozilla/5.0"`,
+				`127.0.0.1 - bob [12/Dec/2021:15:04:06 +0000] "GET /index.html HTTP/1.1" 200 1024 "-" "Mozilla/5.0"`,
+			},
+			expected:  "combined",
+			expectErr: false,
+		},
+		{
+			name: "Common format with a broken line",
+			sample: []string{
+				`127.0.0.1 - - [12/Dec/2021:15:04:05 +0000] "GET /index.html HTTP/1.1" 200 1024`,
+				`garbage`,
+				`10.0.0.1 - - [12/Dec/2021:15:04:06 +0000] "POST /login HTTP/1.1" 302 -`,
+			},
+			expected:  "common",
+			expectErr: false,
+		},
+		{
+			name: "CloudFront with header",
+			sample: []string{
+				"#Version: 1.0",
+				"#Fields: date time c-ip cs-method cs-uri-stem sc-status sc-bytes",
+				"2019-12-04\t21:02:31\t192.0.2.100\tGET\t/index.html\t200\t392",
+			},
+			expected:  "cloudfront",
+			expectErr: false,
+		},
+		{
+			name: "ALB",
+			sample: []string{
+				`http 2018-07-02T22:23:00.186641Z app/lb/50dc 192.168.131.39:2817 10.0.0.1:80 0.000 0.001 0.000 ` +
+					`200 200 34 366 "GET http://www.example.com:80/ HTTP/1.1" "curl/7.46.0" - -`,
+			},
+			expected:  "alb",
+			expectErr: false,
+		},
+		{
+			name:      "Nothing matches",
+			sample:    []string{"first line", "second line"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			name, parser, err := application.DetectFormat(tc.sample, nil)
+
+			if tc.expectErr {
+				assert.ErrorIs(t, err, application.ErrUnknownFormat, "Expected ErrUnknownFormat.")
+			} else {
+				assert.NoError(t, err, "Did not expect an error, but got one.")
+				assert.Equal(t, tc.expected, name, "Detected format mismatch.")
+				assert.NotNil(t, parser, "Parser should not be nil.")
+			}
+		})
+	}
+}
+
+func TestDetectFormat_Formats(t *testing.T) {
+	sample := []string{`{"ts":1639335662,"http.status":"404","path":"/missing"}`}
+
+	formats := application.RegisteredParsers()
+	formats["json"] = func() domain.LogParser {
+		return application.NewJSONParser(map[string]string{"status": "http.status"})
+	}
+
+	name, _, err := application.DetectFormat(sample, formats)
+	assert.NoError(t, err, "Did not expect an error, but got one.")
+	assert.Equal(t, "json", name, "The mapped JSON format should be detected.")
+
+	_, _, err = application.DetectFormat(sample, nil)
+	assert.ErrorIs(t, err, application.ErrUnknownFormat, "Changing a copy should leave the registered formats alone.")
+}
+
+type panickingParser struct{}
+
+func (p *panickingParser) ParseLogLine(_ string) (domain.LogRecord, error) {
+	panic("broken parser")
+}
+
+func TestDetectFormat_PanickingParser(t *testing.T) {
+	sample := []string{`127.0.0.1 - - [12/Dec/2021:15:04:05 +0000] "GET /index.html HTTP/1.1" 200 1024 "-" "Mozilla/5.0"`}
+
+	formats := application.RegisteredParsers()
+	formats["broken"] = func() domain.LogParser { return &panickingParser{} }
+
+	name, _, err := application.DetectFormat(sample, formats)
+	assert.NoError(t, err, "A panicking parser should not fail the detection.")
+	assert.Equal(t, "combined", name, "The other formats should still be scored.")
+}
+
+func TestLogAnalyzer_AutoDetectFormat(t *testing.T) {
+	dir := t.TempDir()
+
+	files := map[string]string{
+		"combined.log": `127.0.0.1 - - [12/Dec/2021:15:04:05 +0000] "GET /index.html HTTP/1.1" 200 1024 "-" "Mozilla/5.0"`,
+		"common.log":   `127.0.0.1 - - [12/Dec/2021:15:04:05 +0000] "GET /about HTTP/1.1" 200 512`,
+		"notes.txt":    "this is not an access log\n",
+		"empty.log":    "",
+	}
+
+	for name, content := range files {
+		err := os.WriteFile(dir+"/"+name, []byte(content+"\n"), 0o600)
+		assert.NoError(t, err, "Failed to create %s.", name)
+	}
+
+	analyzer := application.NewLogAnalyzer([]string{dir}, nil)
+	err := analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", "")
+	assert.NoError(t, err, "Expected no error, but got one.")
+
+	assert.Equal(t, 2, analyzer.Metrics.TotalRequests, "TotalRequests mismatch.")
+	assert.Equal(t, map[string]string{
+		dir + "/combined.log": "combined",
+		dir + "/common.log":   "common",
+		dir + "/notes.txt":    "unknown (skipped)",
+	}, analyzer.Metrics.SourceFormats, "A source without lines should have no detected format.")
+}
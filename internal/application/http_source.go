@@ -0,0 +1,264 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultRetryDelay is the delay before the first retry of a failed download.
+	DefaultRetryDelay = time.Second
+
+	// maxRetryDelay caps the exponentially growing delay between retries.
+	maxRetryDelay = time.Minute
+)
+
+// HTTPConfig configures how URL sources are downloaded.
+type HTTPConfig struct {
+	Client     *http.Client  // Client carrying TLS settings, http.DefaultClient if nil.
+	Header     http.Header   // Headers added to every request of a URL source, such as Authorization.
+	Timeout    time.Duration // Limit for getting the response headers and for every read of the body; none if zero.
+	Retries    int           // Number of retries of a failed request or an interrupted body.
+	RetryDelay time.Duration // Delay before the first retry, doubled for every next one; DefaultRetryDelay if zero.
+}
+
+// openURL starts downloading the URL. The returned body survives dropped connections: it retries
+// with exponential backoff and continues with a Range request from the last byte read, so callers
+// see one uninterrupted stream.
+func (c *HTTPConfig) openURL(rawURL string) (io.ReadCloser, error) {
+	return c.download(&resumableBody{config: c, url: rawURL, header: c.Header})
+}
+
+// openRequest works like openURL, but without the configured Header, which is meant for the
+// server of the URL sources, and calling prepare on every request right before it is sent, for
+// example to sign it.
+func (c *HTTPConfig) openRequest(rawURL string, prepare func(*http.Request)) (io.ReadCloser, error) {
+	return c.download(&resumableBody{config: c, url: rawURL, prepare: prepare})
+}
+
+// download sends the first request of the body.
+func (c *HTTPConfig) download(body *resumableBody) (io.ReadCloser, error) {
+	if err := body.retry(body.open); err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+// resumableBody is the body of a download that is resumed with Range requests after errors.
+type resumableBody struct {
+	config    *HTTPConfig
+	url       string
+	header    http.Header
+	prepare   func(*http.Request)
+	body      io.ReadCloser
+	offset    int64  // Number of body bytes read so far.
+	size      int64  // Length of the whole body, or -1 if unknown.
+	validator string // ETag or Last-Modified of the first response, to make sure a resumed body is the same.
+	cancel    context.CancelFunc
+	idle      *time.Timer // Cancels the request when the server stops responding for Timeout.
+}
+
+// Read implements io.Reader, retrying the download on errors.
+func (b *resumableBody) Read(p []byte) (int, error) {
+	n, err := b.body.Read(p)
+	b.offset += int64(n)
+
+	if b.idle != nil {
+		b.idle.Reset(b.config.Timeout)
+	}
+
+	if errors.Is(err, io.EOF) && (b.size < 0 || b.offset >= b.size) {
+		return n, io.EOF
+	}
+
+	if err == nil {
+		return n, nil
+	}
+
+	fmt.Printf("Download of %s interrupted at byte %d: %v\n", b.url, b.offset, err)
+
+	if err := b.retry(b.open); err != nil {
+		return n, err
+	}
+
+	return n, nil
+}
+
+// Close implements io.Closer.
+func (b *resumableBody) Close() error {
+	b.stopRequest()
+
+	if b.body == nil {
+		return nil
+	}
+
+	return b.body.Close()
+}
+
+// retry calls open until it succeeds, sleeping with exponential backoff between attempts.
+func (b *resumableBody) retry(open func() error) error {
+	delay := b.config.RetryDelay
+	if delay <= 0 {
+		delay = DefaultRetryDelay
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := open()
+
+		var permanent *permanentError
+		if err == nil || errors.As(err, &permanent) || attempt >= b.config.Retries {
+			return err
+		}
+
+		fmt.Printf("Retrying %s in %s: %v\n", b.url, delay, err)
+		time.Sleep(delay)
+
+		delay = min(2*delay, maxRetryDelay)
+	}
+}
+
+// open requests the body from the current offset, replacing the previous response.
+func (b *resumableBody) open() error {
+	if b.body != nil {
+		b.Close()
+		b.body = nil
+	}
+
+	req, err := b.newRequest()
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	client := b.config.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		b.stopRequest()
+		return fmt.Errorf("failed to fetch file from URL %s: %w", b.url, err)
+	}
+
+	if err := b.checkResponse(resp); err != nil {
+		resp.Body.Close()
+		b.stopRequest()
+
+		return err
+	}
+
+	b.body = resp.Body
+
+	return nil
+}
+
+// newRequest creates the GET request for the rest of the body, with a context that ends on timeouts.
+func (b *resumableBody) newRequest() (*http.Request, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, b.url, http.NoBody)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid URL %s: %w", b.url, err)
+	}
+
+	for name, values := range b.header {
+		req.Header[name] = values
+	}
+
+	if b.offset > 0 {
+		req.Header.Set("Range", fmt.Sprintf("bytes=%d-", b.offset))
+
+		if b.validator != "" {
+			req.Header.Set("If-Range", b.validator)
+		}
+	}
+
+	if b.prepare != nil {
+		b.prepare(req)
+	}
+
+	b.cancel = cancel
+	if b.config.Timeout > 0 {
+		b.idle = time.AfterFunc(b.config.Timeout, cancel)
+	}
+
+	return req, nil
+}
+
+// checkResponse makes sure the response continues the body at the current offset.
+func (b *resumableBody) checkResponse(resp *http.Response) error {
+	switch {
+	case b.offset == 0 && resp.StatusCode == http.StatusOK:
+		b.size = resp.ContentLength
+
+		b.validator = resp.Header.Get("ETag")
+		if b.validator == "" || strings.HasPrefix(b.validator, "W/") {
+			b.validator = resp.Header.Get("Last-Modified")
+		}
+
+		return nil
+	case b.offset > 0 && resp.StatusCode == http.StatusPartialContent:
+		if start, ok := contentRangeStart(resp.Header.Get("Content-Range")); !ok || start != b.offset {
+			return &permanentError{fmt.Errorf("server resumed %s at a different byte: %s", b.url, resp.Header.Get("Content-Range"))}
+		}
+
+		return nil
+	case b.offset > 0 && resp.StatusCode == http.StatusOK:
+		return &permanentError{fmt.Errorf("cannot resume %s: the server does not support ranges or the file changed", b.url)}
+	case resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("unexpected HTTP status for URL %s: %s", b.url, resp.Status)
+	default:
+		return &permanentError{fmt.Errorf("unexpected HTTP status for URL %s: %s", b.url, resp.Status)}
+	}
+}
+
+// stopRequest releases the context and the timeout timer of the current request.
+func (b *resumableBody) stopRequest() {
+	if b.idle != nil {
+		b.idle.Stop()
+		b.idle = nil
+	}
+
+	if b.cancel != nil {
+		b.cancel()
+		b.cancel = nil
+	}
+}
+
+// contentRangeStart returns the first byte of a "bytes first-last/size" Content-Range header.
+func contentRangeStart(contentRange string) (int64, bool) {
+	value, ok := strings.CutPrefix(contentRange, "bytes ")
+	if !ok {
+		return 0, false
+	}
+
+	first, _, ok := strings.Cut(value, "-")
+	if !ok {
+		return 0, false
+	}
+
+	start, err := strconv.ParseInt(first, 10, 64)
+
+	return start, err == nil
+}
+
+// permanentError marks download errors that retrying cannot fix.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,140 @@
+package application_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogAnalyzer_HTTPSource(t *testing.T) {
+	content := []byte(strings.Repeat(tailLine, 500))
+
+	// serveLog serves the log with range support, like a static file server.
+	serveLog := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "access.log", time.Time{}, bytes.NewReader(content))
+	}
+
+	tests := []struct {
+		name     string
+		config   application.HTTPConfig
+		handler  func(request int, w http.ResponseWriter, r *http.Request)
+		expected int
+	}{
+		{
+			name:   "Connection dropped in the middle of the body",
+			config: application.HTTPConfig{Retries: 2},
+			handler: func(request int, w http.ResponseWriter, r *http.Request) {
+				if request == 1 {
+					w.Header().Set("ETag", `"v1"`)
+					w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+					_, _ = w.Write(content[:len(content)/2])
+
+					panic(http.ErrAbortHandler)
+				}
+
+				if r.Header.Get("Range") != fmt.Sprintf("bytes=%d-", len(content)/2) || r.Header.Get("If-Range") != `"v1"` {
+					w.WriteHeader(http.StatusBadRequest)
+					return
+				}
+
+				serveLog(w, r)
+			},
+			expected: 500,
+		},
+		{
+			name:   "Server errors are retried",
+			config: application.HTTPConfig{Retries: 2},
+			handler: func(request int, w http.ResponseWriter, r *http.Request) {
+				if request <= 2 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+
+				serveLog(w, r)
+			},
+			expected: 500,
+		},
+		{
+			name:   "Retries are exhausted",
+			config: application.HTTPConfig{Retries: 1},
+			handler: func(request int, w http.ResponseWriter, r *http.Request) {
+				if request <= 2 {
+					w.WriteHeader(http.StatusServiceUnavailable)
+					return
+				}
+
+				serveLog(w, r)
+			},
+			expected: 0,
+		},
+		{
+			name:   "Slow response times out and is retried",
+			config: application.HTTPConfig{Retries: 1, Timeout: 50 * time.Millisecond},
+			handler: func(request int, w http.ResponseWriter, r *http.Request) {
+				if request == 1 {
+					time.Sleep(200 * time.Millisecond)
+				}
+
+				serveLog(w, r)
+			},
+			expected: 500,
+		},
+		{
+			name: "Configured headers are sent",
+			config: application.HTTPConfig{
+				Header: http.Header{"Authorization": {"Bearer secret"}, "X-Tenant": {"edge"}},
+			},
+			handler: func(_ int, w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer secret" || r.Header.Get("X-Tenant") != "edge" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				serveLog(w, r)
+			},
+			expected: 500,
+		},
+		{
+			name:   "Client errors are not retried",
+			config: application.HTTPConfig{Retries: 3},
+			handler: func(request int, w http.ResponseWriter, r *http.Request) {
+				if request == 1 {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+
+				serveLog(w, r)
+			},
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests atomic.Int32
+
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tt.handler(int(requests.Add(1)), w, r)
+			}))
+			defer server.Close()
+
+			analyzer := application.NewLogAnalyzer([]string{server.URL + "/access.log"}, &application.CombinedParser{})
+			analyzer.HTTP = tt.config
+			analyzer.HTTP.RetryDelay = time.Millisecond
+
+			require.NoError(t, analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", ""))
+			assert.Equal(t, tt.expected, analyzer.Metrics.TotalRequests)
+		})
+	}
+}
@@ -0,0 +1,316 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+// JSONFields lists the LogRecord fields that can be mapped to JSON keys.
+// The names match the ones accepted by --filter-field, except for the request timings.
+var JSONFields = []string{
+	"ip", "ident", "user", "timestamp", "request", "method", "url", "protocol",
+	"status", "response_size", "referer", "agent", "request_time", "upstream_time",
+}
+
+// defaultJSONMapping lists the keys tried for every field, covering NGINX escape=json and Caddy access logs.
+var defaultJSONMapping = map[string][]string{
+	"ip":            {"remote_addr", "request.remote_ip", "client_ip", "remote_ip", "ip"},
+	"ident":         {"ident"},
+	"user":          {"remote_user", "user_id", "user"},
+	"timestamp":     {"time_iso8601", "time_local", "@timestamp", "timestamp", "time", "ts", "msec"},
+	"request":       {"request"},
+	"method":        {"request_method", "request.method", "method"},
+	"url":           {"request_uri", "request.uri", "uri", "url", "path"},
+	"protocol":      {"server_protocol", "request.proto", "protocol"},
+	"status":        {"status", "status_code"},
+	"response_size": {"body_bytes_sent", "size", "bytes_sent", "response_size"},
+	"referer":       {"http_referer", "request.headers.Referer", "referer", "referrer"},
+	"agent":         {"http_user_agent", "request.headers.User-Agent", "user_agent", "agent"},
+	"request_time":  {"request_time", "duration"},
+	"upstream_time": {"upstream_response_time", "upstream_time"},
+}
+
+// JSONParser parses JSON-lines access logs. Keys are mapped to LogRecord fields; nested keys
+// are addressed with dots ("request.remote_ip"). Top-level scalar values that are not mapped
+// are kept in LogRecord.Extra.
+type JSONParser struct {
+	mapping map[string][]string
+}
+
+// NewJSONParser creates a JSON-lines parser. The mapping overrides the default keys of the given fields.
+func NewJSONParser(mapping map[string]string) *JSONParser {
+	merged := make(map[string][]string, len(defaultJSONMapping))
+	for field, keys := range defaultJSONMapping {
+		merged[field] = keys
+	}
+
+	for field, key := range mapping {
+		merged[field] = []string{key}
+	}
+
+	return &JSONParser{mapping: merged}
+}
+
+// ParseJSONMapping parses a mapping such as "ip=client.addr,timestamp=@timestamp".
+func ParseJSONMapping(value string) (map[string]string, error) {
+	mapping := make(map[string]string)
+
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		field, key, ok := strings.Cut(pair, "=")
+		field, key = strings.TrimSpace(field), strings.TrimSpace(key)
+
+		if !ok || key == "" {
+			return nil, fmt.Errorf("invalid JSON mapping %q, expected field=key", pair)
+		}
+
+		if _, known := defaultJSONMapping[field]; !known {
+			return nil, fmt.Errorf("unknown JSON mapping field %q (available: %s)", field, strings.Join(JSONFields, ", "))
+		}
+
+		mapping[field] = key
+	}
+
+	return mapping, nil
+}
+
+// ParseLogLine implements domain.LogParser.
+func (p *JSONParser) ParseLogLine(line string) (domain.LogRecord, error) {
+	var log domain.LogRecord
+
+	decoder := json.NewDecoder(strings.NewReader(line))
+	decoder.UseNumber()
+
+	entry := jsonEntry{mapping: p.mapping, used: make(map[string]struct{})}
+	if err := decoder.Decode(&entry.object); err != nil {
+		return log, fmt.Errorf("failed to parse JSON line: %w", err)
+	}
+
+	if err := entry.fillRequired(&log); err != nil {
+		return log, fmt.Errorf("failed to parse line %s: %w", line, err)
+	}
+
+	entry.fillOptional(&log)
+	log.Extra = jsonExtra(entry.object, entry.used)
+
+	return log, nil
+}
+
+// jsonEntry is a single decoded JSON log line together with the keys already mapped to record fields.
+type jsonEntry struct {
+	object  map[string]any
+	mapping map[string][]string
+	used    map[string]struct{}
+}
+
+// lookup returns the value of the first key of the field's mapping that is present in the entry.
+func (e *jsonEntry) lookup(field string) (any, bool) {
+	for _, key := range e.mapping[field] {
+		if value, ok := lookupJSONKey(e.object, key); ok && value != nil {
+			e.used[key] = struct{}{}
+			e.used[strings.SplitN(key, ".", 2)[0]] = struct{}{}
+
+			return value, true
+		}
+	}
+
+	return nil, false
+}
+
+// fillRequired sets the timestamp, status code and response size of the record.
+func (e *jsonEntry) fillRequired(log *domain.LogRecord) error {
+	timestamp, ok := e.lookup("timestamp")
+	if !ok {
+		return fmt.Errorf("no timestamp field")
+	}
+
+	parsedTime, err := parseJSONTime(timestamp)
+	if err != nil {
+		return err
+	}
+
+	log.Timestamp = parsedTime
+
+	status, ok := e.lookup("status")
+	if !ok {
+		return fmt.Errorf("no status field")
+	}
+
+	if log.StatusCode, err = jsonInt(status); err != nil {
+		return fmt.Errorf("failed to parse status code: %v", err)
+	}
+
+	if size, ok := e.lookup("response_size"); ok {
+		if log.ResponseSize, err = jsonInt(size); err != nil {
+			return fmt.Errorf("failed to parse response size: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// fillOptional sets the string fields of the record that are present in the entry.
+func (e *jsonEntry) fillOptional(log *domain.LogRecord) {
+	if request, ok := e.lookup("request"); ok {
+		if parts := strings.Fields(jsonString(request)); len(parts) == 3 {
+			log.Method, log.URL, log.Protocol = parts[0], parts[1], parts[2]
+		}
+	}
+
+	stringFields := []struct {
+		field  string
+		target *string
+	}{
+		{"ip", &log.IP},
+		{"method", &log.Method},
+		{"url", &log.URL},
+		{"protocol", &log.Protocol},
+		{"referer", &log.Referer},
+		{"agent", &log.UserAgent},
+	}
+
+	for _, f := range stringFields {
+		if value, ok := e.lookup(f.field); ok {
+			*f.target = jsonString(value)
+		}
+	}
+
+	if user, ok := e.lookup("user"); ok {
+		log.RemoteUser = optionalField(jsonString(user))
+	}
+
+	if ident, ok := e.lookup("ident"); ok {
+		log.Ident = optionalField(jsonString(ident))
+	}
+
+	if requestTime, ok := e.lookup("request_time"); ok {
+		log.RequestTime, log.HasRequestTime = parseSeconds(jsonString(requestTime))
+	}
+
+	if upstreamTime, ok := e.lookup("upstream_time"); ok {
+		log.UpstreamTime, log.HasUpstreamTime = parseUpstreamTime(jsonString(upstreamTime))
+	}
+}
+
+// lookupJSONKey resolves a dotted key in a decoded JSON object. Keys that contain dots
+// themselves are matched before descending into nested objects.
+func lookupJSONKey(object map[string]any, key string) (any, bool) {
+	if value, ok := object[key]; ok {
+		return value, true
+	}
+
+	for i := 0; i < len(key); i++ {
+		if key[i] != '.' {
+			continue
+		}
+
+		if nested, ok := object[key[:i]].(map[string]any); ok {
+			if value, ok := lookupJSONKey(nested, key[i+1:]); ok {
+				return value, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// jsonString converts a JSON scalar into a string. Arrays, such as Caddy header values, yield their first element.
+func jsonString(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	case []any:
+		if len(v) > 0 {
+			return jsonString(v[0])
+		}
+	}
+
+	return ""
+}
+
+// jsonInt converts a JSON number or numeric string into an int. "-" and empty strings are zero.
+func jsonInt(value any) (int, error) {
+	s := strings.TrimSpace(jsonString(value))
+	if s == "" || s == "-" {
+		return 0, nil
+	}
+
+	if n, err := strconv.Atoi(s); err == nil {
+		return n, nil
+	}
+
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("not a number: %q", s)
+	}
+
+	return int(math.Round(f)), nil
+}
+
+// parseJSONTime parses epoch timestamps (seconds, milliseconds, microseconds or nanoseconds),
+// RFC3339 strings and the NGINX $time_local format.
+func parseJSONTime(value any) (time.Time, error) {
+	s := strings.TrimSpace(jsonString(value))
+
+	if epoch, err := strconv.ParseFloat(s, 64); err == nil {
+		return epochTime(epoch), nil
+	}
+
+	for _, layout := range []string{time.RFC3339Nano, combinedTimeLayout, "2006-01-02 15:04:05"} {
+		if timestamp, err := time.Parse(layout, s); err == nil {
+			return timestamp.UTC(), nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("failed to parse time: %q", s)
+}
+
+// epochTime converts a Unix timestamp of unknown precision into a time, guessing the unit from its magnitude.
+func epochTime(epoch float64) time.Time {
+	switch {
+	case epoch >= 1e17:
+		return time.Unix(0, int64(epoch)).UTC()
+	case epoch >= 1e14:
+		return time.UnixMicro(int64(epoch)).UTC()
+	case epoch >= 1e11:
+		return time.UnixMilli(int64(epoch)).UTC()
+	default:
+		sec, frac := math.Modf(epoch)
+		return time.Unix(int64(sec), int64(math.Round(frac*1e6))*1e3).UTC()
+	}
+}
+
+// jsonExtra collects top-level scalar values that were not mapped to a LogRecord field.
+func jsonExtra(object map[string]any, used map[string]struct{}) map[string]string {
+	var extra map[string]string
+
+	for key, value := range object {
+		if _, ok := used[key]; ok {
+			continue
+		}
+
+		switch value.(type) {
+		case string, json.Number, bool:
+			if extra == nil {
+				extra = make(map[string]string)
+			}
+
+			extra[key] = jsonString(value)
+		}
+	}
+
+	return extra
+}
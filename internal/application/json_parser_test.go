@@ -0,0 +1,118 @@
+package application_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSONParser_ParseLogLine(t *testing.T) {
+	tests := []struct {
+		name      string
+		mapping   map[string]string
+		logLine   string
+		expected  domain.LogRecord
+		expectErr bool
+	}{
+		{
+			name: "NGINX escape=json with default mapping",
+			logLine: `{"time_iso8601":"2021-12-12T19:01:02+00:00","remote_addr":"127.0.0.1","remote_user":"",` +
+				`"request":"GET /index.html HTTP/1.1","status":"200","body_bytes_sent":"1024",` +
+				`"http_referer":"-","http_user_agent":"Mozilla/5.0","request_time":"0.012"}`,
+			expected: domain.LogRecord{
+				IP:             "127.0.0.1",
+				Timestamp:      time.Date(2021, time.December, 12, 19, 1, 2, 0, time.UTC),
+				Method:         "GET",
+				URL:            "/index.html",
+				Protocol:       "HTTP/1.1",
+				StatusCode:     200,
+				ResponseSize:   1024,
+				Referer:        "-",
+				UserAgent:      "Mozilla/5.0",
+				RequestTime:    0.012,
+				HasRequestTime: true,
+			},
+			expectErr: false,
+		},
+		{
+			name: "Caddy access log with default mapping",
+			logLine: `{"level":"info","ts":1639335662.5,"logger":"http.log.access","msg":"handled request",` +
+				`"request":{"remote_ip":"10.0.0.1","proto":"HTTP/2.0","method":"POST","host":"example.com",` +
+				`"uri":"/api","headers":{"User-Agent":["curl/8.0"]}},"user_id":"alice","duration":0.05,"size":42,"status":201}`,
+			expected: domain.LogRecord{
+				IP:           "10.0.0.1",
+				RemoteUser:   "alice",
+				Timestamp:    time.Date(2021, time.December, 12, 19, 1, 2, 500000000, time.UTC),
+				Method:       "POST",
+				URL:          "/api",
+				Protocol:     "HTTP/2.0",
+				StatusCode:   201,
+				ResponseSize: 42,
+				UserAgent:    "curl/8.0",
+				Extra: map[string]string{
+					"level":  "info",
+					"logger": "http.log.access",
+					"msg":    "handled request",
+				},
+				RequestTime:    0.05,
+				HasRequestTime: true,
+			},
+			expectErr: false,
+		},
+		{
+			name:    "Custom mapping with nested and dotted keys and epoch milliseconds",
+			mapping: map[string]string{"ip": "client.address", "timestamp": "event.created", "status": "http.status", "url": "path"},
+			logLine: `{"event.created":1639335662000,"client":{"address":"::1"},"http.status":"404","path":"/missing"}`,
+			expected: domain.LogRecord{
+				IP:         "::1",
+				Timestamp:  time.Date(2021, time.December, 12, 19, 1, 2, 0, time.UTC),
+				URL:        "/missing",
+				StatusCode: 404,
+			},
+			expectErr: false,
+		},
+		{
+			name:      "Not JSON",
+			logLine:   `127.0.0.1 - - [12/Dec/2021:19:01:02 +0000] "GET / HTTP/1.1" 200 1 "-" "-"`,
+			expectErr: true,
+		},
+		{
+			name:      "JSON without status",
+			logLine:   `{"time":"2021-12-12T19:01:02Z","message":"application log"}`,
+			expectErr: true,
+		},
+		{
+			name:      "Invalid timestamp",
+			logLine:   `{"time":"yesterday","status":200}`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := application.NewJSONParser(tt.mapping).ParseLogLine(tt.logLine)
+
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, record)
+			}
+		})
+	}
+}
+
+func TestParseJSONMapping(t *testing.T) {
+	mapping, err := application.ParseJSONMapping("ip=client.ip, timestamp=@timestamp")
+	assert.NoError(t, err, "Did not expect an error, but got one.")
+	assert.Equal(t, map[string]string{"ip": "client.ip", "timestamp": "@timestamp"}, mapping, "Mapping mismatch.")
+
+	_, err = application.ParseJSONMapping("ip")
+	assert.Error(t, err, "Mapping without key should be rejected.")
+
+	_, err = application.ParseJSONMapping("color=red")
+	assert.Error(t, err, "Unknown field should be rejected.")
+}
@@ -0,0 +1,250 @@
+package application
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+const (
+	// SyslogSource is the source name of received syslog messages in the report.
+	SyslogSource = "syslog"
+
+	// syslogQueueSize is the number of received messages buffered for the aggregating goroutine.
+	syslogQueueSize = 4096
+
+	// maxSyslogDatagram is the size of the largest UDP datagram.
+	maxSyslogDatagram = 64 * 1024
+)
+
+// ListenOptions configures LogAnalyzer.Listen.
+type ListenOptions struct {
+	ReportInterval time.Duration                // How often Report is called, never if zero.
+	Signals        <-chan os.Signal             // Every signal received here triggers a Report call.
+	Report         func(*domain.Metrics)        // Renders the metrics collected so far.
+	Queries        <-chan func(*domain.Metrics) // Every function received here is called with the metrics collected so far.
+}
+
+// SyslogReceiver receives syslog messages on a UDP or TCP address.
+type SyslogReceiver struct {
+	packets  net.PacketConn
+	listener net.Listener
+}
+
+// NewSyslogReceiver starts listening on an address such as udp://:5514 or tcp://0.0.0.0:5514.
+func NewSyslogReceiver(address string) (*SyslogReceiver, error) {
+	network, hostPort, ok := strings.Cut(address, "://")
+	if !ok {
+		return nil, fmt.Errorf("invalid syslog address %s: expected udp://host:port or tcp://host:port", address)
+	}
+
+	switch network {
+	case "udp", "udp4", "udp6":
+		packets, err := net.ListenPacket(network, hostPort)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+
+		return &SyslogReceiver{packets: packets}, nil
+	case "tcp", "tcp4", "tcp6":
+		listener, err := net.Listen(network, hostPort)
+		if err != nil {
+			return nil, fmt.Errorf("failed to listen on %s: %w", address, err)
+		}
+
+		return &SyslogReceiver{listener: listener}, nil
+	default:
+		return nil, fmt.Errorf("unsupported syslog network %s in %s", network, address)
+	}
+}
+
+// Addr returns the address the receiver listens on.
+func (r *SyslogReceiver) Addr() net.Addr {
+	if r.packets != nil {
+		return r.packets.LocalAddr()
+	}
+
+	return r.listener.Addr()
+}
+
+// Close stops listening.
+func (r *SyslogReceiver) Close() error {
+	if r.packets != nil {
+		return r.packets.Close()
+	}
+
+	return r.listener.Close()
+}
+
+// serve passes every received message to the channel until the context is canceled.
+func (r *SyslogReceiver) serve(ctx context.Context, messages chan<- string) error {
+	stop := context.AfterFunc(ctx, func() { r.Close() })
+	defer stop()
+
+	var err error
+	if r.packets != nil {
+		err = r.servePackets(ctx, messages)
+	} else {
+		err = r.serveStreams(ctx, messages)
+	}
+
+	if ctx.Err() != nil {
+		return nil
+	}
+
+	return err
+}
+
+// servePackets receives one message per UDP datagram.
+func (r *SyslogReceiver) servePackets(ctx context.Context, messages chan<- string) error {
+	buf := make([]byte, maxSyslogDatagram)
+
+	for {
+		n, _, err := r.packets.ReadFrom(buf)
+		if err != nil {
+			return err
+		}
+
+		if !sendMessage(ctx, messages, strings.TrimRight(string(buf[:n]), "\r\n")) {
+			return nil
+		}
+	}
+}
+
+// serveStreams accepts TCP connections and receives the framed messages of each.
+func (r *SyslogReceiver) serveStreams(ctx context.Context, messages chan<- string) error {
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		conn, err := r.listener.Accept()
+		if err != nil {
+			return err
+		}
+
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			stop := context.AfterFunc(ctx, func() { conn.Close() })
+			defer stop()
+			defer conn.Close()
+
+			reader := bufio.NewReader(conn)
+
+			for {
+				message, err := readSyslogFrame(reader)
+				if err != nil {
+					return
+				}
+
+				if !sendMessage(ctx, messages, message) {
+					return
+				}
+			}
+		}()
+	}
+}
+
+// sendMessage passes a message to the channel, reporting false if the context is canceled first.
+func sendMessage(ctx context.Context, messages chan<- string, message string) bool {
+	select {
+	case messages <- message:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Listen analyzes the syslog messages arriving at the receivers until the context is canceled,
+// closing the receivers when it returns. The syslog envelope of every message is stripped and the
+// rest is parsed as a log line, so NGINX can send its access log with access_log syslog:.
+//
+// The receivers only read messages; parsing, aggregation and reporting all happen on the calling
+// goroutine, so Report and Queries may read the metrics without synchronization.
+func (a *LogAnalyzer) Listen(ctx context.Context, receivers []*SyslogReceiver, from, to time.Time,
+	filterField, filterValue string, opts ListenOptions) error {
+	ctx, cancel := context.WithCancel(ctx)
+
+	var wg sync.WaitGroup
+
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	messages := make(chan string, syslogQueueSize)
+	errs := make(chan error, len(receivers))
+
+	for _, receiver := range receivers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			errs <- receiver.serve(ctx, messages)
+		}()
+	}
+
+	var reports <-chan time.Time
+
+	if opts.ReportInterval > 0 {
+		reportTicker := time.NewTicker(opts.ReportInterval)
+		defer reportTicker.Stop()
+
+		reports = reportTicker.C
+	}
+
+	sink := &lineSink{analyzer: a, source: SyslogSource, from: from, to: to, filterField: filterField, filterValue: filterValue}
+
+	report := func(render func(*domain.Metrics)) {
+		sink.flush()
+		a.calculateRPS()
+
+		if render != nil {
+			render(a.Metrics)
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			sink.drain(messages)
+			report(nil)
+
+			return nil
+		case message := <-messages:
+			sink.add(StripSyslogEnvelope(message))
+		case err := <-errs:
+			if err != nil {
+				return fmt.Errorf("error receiving syslog messages: %w", err)
+			}
+		case <-reports:
+			report(opts.Report)
+		case <-opts.Signals:
+			report(opts.Report)
+		case query := <-opts.Queries:
+			report(query)
+		}
+	}
+}
+
+// drain processes the syslog messages already queued.
+func (s *lineSink) drain(messages <-chan string) {
+	for {
+		select {
+		case message := <-messages:
+			s.add(StripSyslogEnvelope(message))
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogAnalyzer_Listen(t *testing.T) {
+	udp, err := application.NewSyslogReceiver("udp://127.0.0.1:0")
+	require.NoError(t, err)
+
+	tcp, err := application.NewSyslogReceiver("tcp://127.0.0.1:0")
+	require.NoError(t, err)
+
+	analyzer := application.NewLogAnalyzer(nil, nil)
+	queries := make(chan func(*domain.Metrics))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- analyzer.Listen(ctx, []*application.SyslogReceiver{udp, tcp}, time.Time{}, time.Time{}, "", "",
+			application.ListenOptions{Queries: queries})
+	}()
+
+	message := "<190>Oct 17 10:00:00 edge-1 nginx: " + tailLine[:len(tailLine)-1]
+
+	udpConn, err := net.Dial("udp", udp.Addr().String())
+	require.NoError(t, err)
+
+	defer udpConn.Close()
+
+	for range 30 {
+		_, err = udpConn.Write([]byte(message))
+		require.NoError(t, err)
+	}
+
+	tcpConn, err := net.Dial("tcp", tcp.Addr().String())
+	require.NoError(t, err)
+
+	for range 10 {
+		_, err = fmt.Fprintf(tcpConn, "%d %s", len(message), message)
+		require.NoError(t, err)
+	}
+
+	// Frames without octet counting are terminated by newlines.
+	_, err = fmt.Fprintf(tcpConn, "%s\n%s\n", message, message)
+	require.NoError(t, err)
+	require.NoError(t, tcpConn.Close())
+
+	totalRequests := func() int {
+		result := make(chan int)
+		queries <- func(metrics *domain.Metrics) { result <- metrics.TotalRequests }
+
+		return <-result
+	}
+
+	// UDP gives no delivery guarantee, but datagrams on the loopback interface are not lost in practice.
+	assert.Eventually(t, func() bool { return totalRequests() == 42 }, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	require.NoError(t, <-done)
+
+	assert.Equal(t, "combined", analyzer.Metrics.SourceFormats[application.SyslogSource])
+	assert.Equal(t, 42, analyzer.Metrics.ResourceStats["/index.html"].Requests)
+
+	_, err = net.Dial("tcp", tcp.Addr().String())
+	assert.Error(t, err, "Receivers should be closed when listening stops.")
+}
+
+func TestLogAnalyzer_ListenDropsLongLines(t *testing.T) {
+	tcp, err := application.NewSyslogReceiver("tcp://127.0.0.1:0")
+	require.NoError(t, err)
+
+	analyzer := application.NewLogAnalyzer(nil, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+
+	go func() {
+		done <- analyzer.Listen(ctx, []*application.SyslogReceiver{tcp}, time.Time{}, time.Time{}, "", "",
+			application.ListenOptions{})
+	}()
+
+	conn, err := net.Dial("tcp", tcp.Addr().String())
+	require.NoError(t, err)
+
+	defer conn.Close()
+
+	// A line without a newline, longer than any syslog message, is never buffered as a whole.
+	go func() { _, _ = conn.Write([]byte(strings.Repeat("a", 2<<20))) }()
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+
+	_, err = conn.Read(make([]byte, 1))
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		assert.False(t, netErr.Timeout(), "The connection should be dropped.")
+	}
+
+	assert.Error(t, err, "The connection should be dropped.")
+
+	cancel()
+	require.NoError(t, <-done)
+}
+
+func TestNewSyslogReceiver_InvalidAddress(t *testing.T) {
+	for _, address := range []string{":5514", "http://:5514", "udp://:notaport"} {
+		_, err := application.NewSyslogReceiver(address)
+		assert.Error(t, err, address)
+	}
+}
@@ -1,48 +1,185 @@
 package application
 
 import (
+	"errors"
 	"fmt"
+	"io"
 	"os"
-	"path/filepath"
+	"runtime"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/abakunov/log-analyzer/internal/domain"
 )
 
 type LogAnalyzer struct {
-	Paths   []string
-	Metrics *domain.Metrics
+	Paths         []string
+	Parser        domain.LogParser
+	Formats       ParserRegistry // Log formats tried when Parser is nil; the registered ones if nil.
+	Metrics       *domain.Metrics
+	Stdin         io.Reader // Source of the StdinPath path, os.Stdin by default.
+	Workers       int       // Number of sources analyzed concurrently.
+	ParseWorkers  int       // Number of goroutines parsing the lines of a single source.
+	HTTP          HTTPConfig
+	S3            S3Config
+	SourceFilter  SourceFilter  // Selects the files of local directories.
+	BucketWidth   time.Duration // Width of the timeline buckets; zero chooses it from the time span.
+	URLNormalizer URLNormalizer // Turns request URLs into the resources the metrics are grouped by.
+
+	// Checkpoints of the local files analyzed by earlier runs, by path; nil disables checkpointing.
+	// AnalyzeLogs updates them with the files of this run.
+	Checkpoints map[string]*domain.Checkpoint
+
+	checkpoint *domain.Checkpoint // Checkpoint of the file analyzed by a worker copy of the analyzer.
 }
 
-// NewLogAnalyzer creates a new LogAnalyzer.
-func NewLogAnalyzer(paths []string) *LogAnalyzer {
+// NewLogAnalyzer creates a new LogAnalyzer that parses lines with the given parser.
+// A nil parser enables detection of the log format for every source.
+func NewLogAnalyzer(paths []string, parser domain.LogParser) *LogAnalyzer {
 	return &LogAnalyzer{
-		Paths:   paths,
-		Metrics: domain.NewMetrics(paths),
+		Paths:        paths,
+		Parser:       parser,
+		Metrics:      domain.NewMetrics(paths),
+		Stdin:        os.Stdin,
+		Workers:      runtime.NumCPU(),
+		ParseWorkers: runtime.NumCPU(),
 	}
 }
 
 // AnalyzeLogs processes all log files or URLs based on the provided paths.
+// Sources are analyzed by a pool of Workers goroutines, each collecting its own partial metrics.
+// The partial metrics are merged in source order, so the result does not depend on the number of workers.
 func (a *LogAnalyzer) AnalyzeLogs(from, to time.Time, filterField, filterValue string) error {
+	sources := a.dedupeSources(OrderRotated(a.collectSources()))
+	partials := make([]*domain.Metrics, len(sources))
+	checkpoints := make([]*domain.Checkpoint, len(sources))
+	jobs := make(chan int)
+
+	var wg sync.WaitGroup
+
+	for range min(max(a.Workers, 1), len(sources)) {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for i := range jobs {
+				partials[i], checkpoints[i] = a.analyzeSource(sources[i], from, to, filterField, filterValue)
+			}
+		}()
+	}
+
+	for i := range sources {
+		jobs <- i
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	warnOverlaps(sources, partials)
+
+	for i, partial := range partials {
+		a.mergeSource(sources[i], partial)
+	}
+
+	if a.Checkpoints != nil {
+		a.updateCheckpoints(sources, checkpoints)
+	}
+
+	a.calculateRPS()
+
+	return nil
+}
+
+// collectSources expands the paths into the list of sources to analyze: directories are walked
+// for files, URL directory listings and S3 prefixes are expanded into the listed files and objects,
+// while other URLs and the standard input are sources on their own.
+func (a *LogAnalyzer) collectSources() []string {
+	var sources []string
+
 	for _, path := range a.Paths {
-		err := a.processPath(path, from, to, filterField, filterValue)
+		if path == StdinPath {
+			sources = append(sources, path)
+			continue
+		}
+
+		if IsURL(path) || IsS3(path) {
+			urls, err := a.expandRemote(path)
+			if err != nil {
+				fmt.Printf("Error processing path %s: %v\n", path, err)
+				continue
+			}
+
+			if !slices.Equal(urls, []string{path}) {
+				a.replaceFileName(path, urls)
+			}
+
+			sources = append(sources, urls...)
+
+			continue
+		}
+
+		files, err := a.SourceFilter.walk(path)
 		if err != nil {
 			fmt.Printf("Error processing path %s: %v\n", path, err)
 		}
+
+		sources = append(sources, files...)
 	}
 
-	a.calculateRPS()
+	return sources
+}
 
-	return nil
+// analyzeSource processes a single source with a copy of the analyzer and returns the metrics collected
+// from it, along with the checkpoint of the source if it is a local file and checkpointing is enabled.
+func (a *LogAnalyzer) analyzeSource(source string, from, to time.Time,
+	filterField, filterValue string) (*domain.Metrics, *domain.Checkpoint) {
+	worker := *a
+	worker.Paths = []string{source}
+	worker.Metrics = domain.NewMetrics(worker.Paths)
+
+	err := worker.processPath(source, from, to, filterField, filterValue)
+	if err != nil && !errors.Is(err, errSourceSkipped) {
+		fmt.Printf("Error processing path %s: %v\n", source, err)
+	}
+
+	return worker.Metrics, worker.checkpoint
+}
+
+// mergeSource merges the metrics of a single source into the analyzer metrics.
+func (a *LogAnalyzer) mergeSource(source string, partial *domain.Metrics) {
+	// Archives and the standard input are reported under the names of their members.
+	if !slices.Equal(partial.FileNames, []string{source}) {
+		a.replaceFileName(source, partial.FileNames)
+	}
+
+	partial.FileNames = nil
+	a.Metrics.Merge(partial)
 }
 
-// processPath determines whether the path is a URL or local file and processes it.
+// processPath determines whether the source is a URL, an S3 object, the standard input or a local file and processes it.
 func (a *LogAnalyzer) processPath(path string, from, to time.Time, filterField, filterValue string) error {
+	if path == StdinPath {
+		return a.processStdin(from, to, filterField, filterValue)
+	}
+
 	if IsURL(path) {
 		return a.processURLPath(path, from, to, filterField, filterValue)
 	}
 
-	return a.processLocalPath(path, from, to, filterField, filterValue)
+	if IsS3(path) {
+		return a.processS3Path(path, from, to, filterField, filterValue)
+	}
+
+	fmt.Printf("Processing file: %s\n", path)
+
+	err := a.processFile(path, from, to, filterField, filterValue)
+	if err != nil {
+		return fmt.Errorf("error processing file %s: %w", path, err)
+	}
+
+	return nil
 }
 
 // processURLPath processes a URL path.
@@ -57,27 +194,16 @@ func (a *LogAnalyzer) processURLPath(path string, from, to time.Time, filterFiel
 	return nil
 }
 
-// processLocalPath processes a local path using filepath.Walk.
-func (a *LogAnalyzer) processLocalPath(path string, from, to time.Time, filterField, filterValue string) error {
-	return filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
-
-		// Skip directories.
-		if info.IsDir() {
-			return nil
-		}
-
-		fmt.Printf("Processing file: %s\n", filePath)
+// processS3Path processes an S3 object path.
+func (a *LogAnalyzer) processS3Path(path string, from, to time.Time, filterField, filterValue string) error {
+	fmt.Printf("Processing object: %s\n", path)
 
-		err = a.processFile(filePath, from, to, filterField, filterValue)
-		if err != nil {
-			return fmt.Errorf("error processing file %s: %w", filePath, err)
-		}
+	err := a.processS3(path, from, to, filterField, filterValue)
+	if err != nil {
+		return fmt.Errorf("error processing object %s: %w", path, err)
+	}
 
-		return nil
-	})
+	return nil
 }
 
 // calculateRPS calculates Requests Per Second (RPS).
@@ -1,11 +1,18 @@
 package application_test
 
 import (
+	"fmt"
+	"maps"
+	"math/rand"
 	"os"
+	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -31,7 +38,8 @@ func TestLogAnalyzer_AnalyzeLogs(t *testing.T) {
 	// Данные для лог-файла.
 	logData := `127.0.0.1 - - [12/Dec/2021:15:04:05 +0000] "GET /index.html HTTP/1.1" 200 1024 "http://example.com" "Mozilla/5.0"
 127.0.0.1 - - [12/Dec/2021:16:04:05 +0000] "POST /submit HTTP/1.1" 404 - "-" "-"
-192.168.1.1 - - [13/Dec/2021:15:04:05 +0000] "GET /home HTTP/1.1" 200 512 "-" "Mozilla/5.0"`
+192.168.1.1 - - [13/Dec/2021:15:04:05 +0000] "GET /home HTTP/1.1" 200 512 "-" "Mozilla/5.0"
+192.168.1.2 - admin [14/Dec/2021:15:04:05 +0000] "GET /admin HTTP/1.1" 401 0 "-" "curl/8.0"`
 
 	// Генерация тестового лог-файла.
 	err := mockGenerator.GenerateLogFile("testdata/logfile.log", logData)
@@ -88,7 +96,7 @@ func TestLogAnalyzer_AnalyzeLogs(t *testing.T) {
 			filterField:  "method",
 			filterValue:  "GET",
 			expectedErr:  false,
-			expectedReqs: 2,
+			expectedReqs: 3,
 		},
 		{
 			name:         "Filter by Status Code",
@@ -118,7 +126,7 @@ func TestLogAnalyzer_AnalyzeLogs(t *testing.T) {
 			filterField:  "protocol",
 			filterValue:  "HTTP/1.1",
 			expectedErr:  false,
-			expectedReqs: 3,
+			expectedReqs: 4,
 		},
 		{
 			name:         "Filter by User Agent",
@@ -140,12 +148,22 @@ func TestLogAnalyzer_AnalyzeLogs(t *testing.T) {
 			expectedErr:  false,
 			expectedReqs: 1,
 		},
+		{
+			name:         "Filter by User",
+			paths:        []string{"testdata/logfile.log"},
+			from:         time.Time{},
+			to:           time.Time{},
+			filterField:  "user",
+			filterValue:  "adm*",
+			expectedErr:  false,
+			expectedReqs: 1,
+		},
 	}
 
 	// Итерация через тестовые сценарии.
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			analyzer := application.NewLogAnalyzer(tc.paths)
+			analyzer := application.NewLogAnalyzer(tc.paths, &application.CombinedParser{})
 			err := analyzer.AnalyzeLogs(tc.from, tc.to, tc.filterField, tc.filterValue)
 
 			// Проверка на ошибку.
@@ -160,3 +178,142 @@ func TestLogAnalyzer_AnalyzeLogs(t *testing.T) {
 		})
 	}
 }
+
+// writeRandomLog writes a combined log file with lines generated from the seed.
+func writeRandomLog(tb testing.TB, path string, lines int, seed int64) {
+	tb.Helper()
+
+	rng := rand.New(rand.NewSource(seed))
+	methods := []string{"GET", "POST", "PUT"}
+	statuses := []int{200, 301, 404, 500}
+
+	var sb strings.Builder
+
+	for line := 0; line < lines; line++ {
+		fmt.Fprintf(&sb, "10.0.%d.%d - user%d [12/Dec/2021:%02d:%02d:%02d +0000] \"%s /page/%d HTTP/1.1\" %d %d \"-\" \"agent\"\n",
+			rng.Intn(4), rng.Intn(255), rng.Intn(3), rng.Intn(24), rng.Intn(60), rng.Intn(60),
+			methods[rng.Intn(len(methods))], rng.Intn(20), statuses[rng.Intn(len(statuses))], rng.Intn(100000))
+	}
+
+	err := os.WriteFile(path, []byte(sb.String()), 0o600)
+	assert.NoError(tb, err, "Failed to create log file.")
+}
+
+func TestLogAnalyzer_ConcurrentMatchesSequential(t *testing.T) {
+	dir := t.TempDir()
+
+	for file := 0; file < 12; file++ {
+		writeRandomLog(t, filepath.Join(dir, fmt.Sprintf("access-%02d.log", file)), 200, int64(file))
+	}
+
+	analyze := func(workers int) *domain.Metrics {
+		analyzer := application.NewLogAnalyzer([]string{dir}, nil)
+		analyzer.Workers = workers
+
+		err := analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", "")
+		assert.NoError(t, err, "Expected no error, but got one.")
+
+		return analyzer.Metrics
+	}
+
+	sequential := analyze(1)
+	assert.Equal(t, 12*200, sequential.TotalRequests, "TotalRequests mismatch.")
+
+	for _, workers := range []int{2, 4, 16} {
+		assert.Equal(t, sequential, analyze(workers), "Metrics with %d workers differ from a sequential run.", workers)
+	}
+}
+
+func TestLogAnalyzer_Latency(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	logData := `10.0.0.1 [12/Dec/2021:15:04:05 +0000] "GET /api HTTP/1.1" 200 0.100 0.090
+10.0.0.1 [12/Dec/2021:15:04:06 +0000] "GET /api HTTP/1.1" 200 0.300 0.280
+10.0.0.2 [12/Dec/2021:15:04:07 +0000] "GET /static.css HTTP/1.1" 200 - -
+10.0.0.2 [12/Dec/2021:15:04:08 +0000] "GET /upstream HTTP/1.1" 502 - 0.500`
+
+	err := os.WriteFile(path, []byte(logData), 0o600)
+	assert.NoError(t, err, "Failed to create log file.")
+
+	parser, err := application.NewNginxParser(
+		`$remote_addr [$time_local] "$request" $status $request_time $upstream_response_time`)
+	assert.NoError(t, err)
+
+	analyzer := application.NewLogAnalyzer([]string{path}, parser)
+	err = analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", "")
+	assert.NoError(t, err, "Expected no error, but got one.")
+
+	metrics := analyzer.Metrics
+	assert.Equal(t, 4, metrics.TotalRequests, "TotalRequests mismatch.")
+	assert.Equal(t, 1, metrics.UntimedRequests, "Lines without timing should be counted separately.")
+	assert.Equal(t, 3, metrics.Latency.Count, "Latency count mismatch.")
+	assert.InDelta(t, 0.3, metrics.Latency.Average(), 1e-9, "Untimed lines should not count as zero latency.")
+	assert.InDelta(t, 0.5, metrics.Latency.Max, 1e-9, "The upstream time should be used without a request time.")
+
+	api := metrics.ResourceStats["/api"].Latency
+	assert.Equal(t, 2, api.Count, "Latency count of /api mismatch.")
+	assert.InDelta(t, 0.2, api.Average(), 1e-9, "Average latency of /api mismatch.")
+	assert.InDelta(t, 0.1, api.Durations.Quantile(0.5), 0.1*domain.DefaultSketchAccuracy, "p50 of /api mismatch.")
+	assert.Nil(t, metrics.ResourceStats["/static.css"].Latency, "Resources without timing should have no latency.")
+}
+
+func TestLogAnalyzer_BucketWidth(t *testing.T) {
+	dir := t.TempDir()
+
+	for file := 0; file < 3; file++ {
+		writeRandomLog(t, filepath.Join(dir, fmt.Sprintf("access-%02d.log", file)), 100, int64(file))
+	}
+
+	analyzer := application.NewLogAnalyzer([]string{dir}, nil)
+	analyzer.BucketWidth = 10 * time.Minute
+
+	err := analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", "")
+	assert.NoError(t, err, "Expected no error, but got one.")
+
+	timeline := analyzer.Metrics.Timeline
+	assert.Equal(t, 10*time.Minute, timeline.Width, "The bucket width should be the configured one.")
+	assert.True(t, timeline.Fixed, "The configured bucket width should be fixed.")
+
+	requests := 0
+	for _, start := range timeline.Starts() {
+		requests += timeline.Bucket(start).Requests
+	}
+
+	assert.Equal(t, analyzer.Metrics.TotalRequests, requests, "Every request should be in a bucket.")
+}
+
+func TestLogAnalyzer_URLNormalizer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	logData := `10.0.0.1 - - [12/Dec/2021:15:04:05 +0000] "GET /users/123 HTTP/1.1" 200 10 "-" "-"
+10.0.0.1 - - [12/Dec/2021:15:04:06 +0000] "GET /users/456?x=1 HTTP/1.1" 404 20 "-" "-"
+10.0.0.2 - - [12/Dec/2021:15:04:07 +0000] "GET /users/789/ HTTP/1.1" 200 30 "-" "-"
+10.0.0.2 - - [12/Dec/2021:15:04:08 +0000] "POST /users/789/orders HTTP/1.1" 201 40 "-" "-"`
+
+	err := os.WriteFile(path, []byte(logData), 0o600)
+	assert.NoError(t, err, "Failed to create log file.")
+
+	normalizer, err := application.ParseURLNormalizer([]string{"all"}, []string{"/users/:id/orders"})
+	assert.NoError(t, err)
+
+	analyzer := application.NewLogAnalyzer([]string{path}, &application.CombinedParser{})
+	analyzer.URLNormalizer = normalizer
+
+	err = analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "url", "/users/7*")
+	assert.NoError(t, err, "Expected no error, but got one.")
+	assert.ElementsMatch(t, []string{"/users/{id}", "/users/:id/orders"}, slices.Collect(maps.Keys(analyzer.Metrics.ResourceStats)),
+		"Resources should be grouped by normalized URL, while filters see the logged URL.")
+	assert.Equal(t, 1, analyzer.Metrics.ResourceStats["/users/{id}"].Requests, "Requests of /users/{id} mismatch.")
+
+	analyzer = application.NewLogAnalyzer([]string{path}, &application.CombinedParser{})
+	analyzer.URLNormalizer = normalizer
+
+	err = analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", "")
+	assert.NoError(t, err, "Expected no error, but got one.")
+	assert.Equal(t, 3, analyzer.Metrics.ResourceStats["/users/{id}"].Requests, "Requests of /users/{id} mismatch.")
+	assert.Equal(t, 60, analyzer.Metrics.ResourceStats["/users/{id}"].Bytes, "Bytes of /users/{id} mismatch.")
+	assert.Equal(t, &domain.ClientStats{
+		Requests:  2,
+		Bytes:     70,
+		Resources: map[string]struct{}{"/users/{id}": {}, "/users/:id/orders": {}},
+	}, analyzer.Metrics.Clients["10.0.0.2"], "Clients should count distinct normalized resources.")
+	assert.Equal(t, 1, analyzer.Metrics.Clients["10.0.0.1"].Errors, "Errors of 10.0.0.1 mismatch.")
+}
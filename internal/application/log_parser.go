@@ -9,33 +9,81 @@ import (
 	"github.com/abakunov/log-analyzer/internal/domain"
 )
 
-func ParseLogLine(line string) (domain.LogRecord, error) {
+var (
+	// The request ends with an unescaped quote, and the referer and the user agent may contain
+	// backslash-escaped quotes.
+	combinedPattern = regexp.MustCompile(`^(\S+) (\S+) (\S+) \[([^\]]+)\] "(\S+) (\S+) (\S*[^\s\\](?:\\\\)*|(?:\\\\)+)" ` +
+		`(\d+) (\d+|-) "((?:[^"\\]|\\[\s\S])*)" "((?:[^"\\]|\\[\s\S])*)"$`)
+	commonPattern = regexp.MustCompile(
+		`^(\S+) (\S+) (\S+) \[([^\]]+)\] "(\S+) (\S+) (\S+)" (\d+) (\d+|-)$`)
+)
+
+// CombinedParser parses lines in the NGINX/Apache combined log format.
+type CombinedParser struct{}
+
+// ParseLogLine implements domain.LogParser.
+func (p *CombinedParser) ParseLogLine(line string) (domain.LogRecord, error) {
 	var log domain.LogRecord
 
-	pattern := `^(\S+) - - \[([^\]]+)\] "(\S+) (\S+) (\S+)" (\d+) (\d+|-) "([^"]*)" "([^"]*)"$`
-	re := regexp.MustCompile(pattern)
-	matches := re.FindStringSubmatch(line)
+	err := parseCombined(line, &log)
+
+	return log, err
+}
+
+// CommonParser parses lines in the Apache common log format (combined without referer and user agent).
+type CommonParser struct{}
 
+// ParseLogLine implements domain.LogParser.
+func (p *CommonParser) ParseLogLine(line string) (domain.LogRecord, error) {
+	matches := commonPattern.FindStringSubmatch(line)
 	if len(matches) != 10 {
-		return log, fmt.Errorf("failed to parse line: %s", line)
+		return domain.LogRecord{}, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	return parseCommonFields(matches)
+}
+
+// ParseLogLine parses a single line in the combined log format with a regular expression.
+// CombinedParser uses the faster hand-written parser, which accepts the same lines.
+func ParseLogLine(line string) (domain.LogRecord, error) {
+	matches := combinedPattern.FindStringSubmatch(line)
+	if len(matches) != 12 {
+		return domain.LogRecord{}, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	log, err := parseCommonFields(matches)
+	if err != nil {
+		return log, err
 	}
 
+	log.Referer = matches[10]
+	log.UserAgent = matches[11]
+
+	return log, nil
+}
+
+// parseCommonFields fills the fields shared by the common and combined formats from regexp submatches.
+func parseCommonFields(matches []string) (domain.LogRecord, error) {
+	var log domain.LogRecord
+
 	log.IP = matches[1]
+	log.Ident = optionalField(matches[2])
+	log.RemoteUser = optionalField(matches[3])
 
 	// Parse timestamp and ensure it's in UTC.
-	timestamp, err := time.Parse("02/Jan/2006:15:04:05 -0700", matches[2])
+	timestamp, err := time.Parse(combinedTimeLayout, matches[4])
 	if err != nil {
 		return log, fmt.Errorf("failed to parse time: %v", err)
 	}
 
 	log.Timestamp = timestamp.UTC()
 
-	log.Method = matches[3]
-	log.URL = matches[4]
-	log.Protocol = matches[5]
+	log.Method = matches[5]
+	log.URL = matches[6]
+	log.Protocol = matches[7]
 
 	// Parse status code.
-	statusCode, err := strconv.Atoi(matches[6])
+	statusCode, err := strconv.Atoi(matches[8])
 	if err != nil {
 		return log, fmt.Errorf("failed to parse status code: %v", err)
 	}
@@ -43,19 +91,21 @@ func ParseLogLine(line string) (domain.LogRecord, error) {
 	log.StatusCode = statusCode
 
 	// Parse response size.
-	if matches[7] != "-" {
-		responseSize, err := strconv.Atoi(matches[7])
-		if err != nil {
-			return log, fmt.Errorf("failed to parse response size: %v", err)
-		}
-
-		log.ResponseSize = responseSize
-	} else {
-		log.ResponseSize = 0
+	responseSize, err := parseSize(matches[9])
+	if err != nil {
+		return log, fmt.Errorf("failed to parse response size: %v", err)
 	}
 
-	log.Referer = matches[8]
-	log.UserAgent = matches[9]
+	log.ResponseSize = responseSize
 
 	return log, nil
 }
+
+// optionalField converts the "-" placeholder used for missing values into an empty string.
+func optionalField(value string) string {
+	if value == "-" {
+		return ""
+	}
+
+	return value
+}
@@ -48,6 +48,24 @@ func TestParseLogLine(t *testing.T) {
 			},
 			expectErr: false,
 		},
+		{
+			name:    "Valid log line with remote user and ident",
+			logLine: `10.0.0.5 ident42 alice [12/Dec/2021:19:01:02 +0000] "GET /admin HTTP/1.1" 200 512 "-" "curl/8.0"`,
+			expected: domain.LogRecord{
+				IP:           "10.0.0.5",
+				Ident:        "ident42",
+				RemoteUser:   "alice",
+				Timestamp:    time.Date(2021, time.December, 12, 19, 1, 2, 0, time.UTC),
+				Method:       "GET",
+				URL:          "/admin",
+				Protocol:     "HTTP/1.1",
+				StatusCode:   200,
+				ResponseSize: 512,
+				Referer:      "-",
+				UserAgent:    "curl/8.0",
+			},
+			expectErr: false,
+		},
 		{
 			name:      "Invalid log line format",
 			logLine:   `Invalid log line format`,
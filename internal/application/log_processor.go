@@ -2,47 +2,227 @@ package application
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
 )
 
-// processLogs processes logs from an io.Reader line by line.
-func (a *LogAnalyzer) processLogs(reader io.Reader, from, to time.Time, filterField, filterValue string) error {
-	scanner := bufio.NewScanner(reader)
+// parseChunkLines is the number of lines the reader hands to a parser goroutine at once.
+const parseChunkLines = 4096
+
+// errSourceSkipped is returned for a source none of the log formats matches, after telling the user that it is skipped.
+var errSourceSkipped = errors.New("source skipped")
+
+// processLogs processes logs of the named source from an io.Reader line by line.
+func (a *LogAnalyzer) processLogs(reader io.Reader, source string, from, to time.Time, filterField, filterValue string) error {
+	buffered := bufio.NewReaderSize(reader, detectSampleBytes)
+
+	parser, err := a.parserFor(source, buffered)
+	if errors.Is(err, ErrUnknownFormat) {
+		fmt.Printf("Skipping %s: none of the log formats %v matches its first lines\n\n", source, a.formatNames())
+		return errSourceSkipped
+	}
+
+	scanner := bufio.NewScanner(buffered)
+
+	var lineCount int
+
+	_, stateful := parser.(domain.StatefulParser)
+
+	switch {
+	case parser == nil:
+		// The source has no lines to detect the format from, only blank ones.
+		for scanner.Scan() {
+			lineCount++
+		}
+	case stateful || a.ParseWorkers <= 1:
+		// Stateful parsers depend on earlier lines, such as a #Fields header, so they cannot run in parallel.
+		lineCount = a.parseSequential(scanner, parser, from, to, filterField, filterValue)
+	default:
+		lineCount = a.parseParallel(scanner, parser, from, to, filterField, filterValue)
+	}
+
+	fmt.Printf("Processed %d lines from log source\n", lineCount)
+	fmt.Println()
+
+	return scanner.Err()
+}
+
+// parseSequential parses and aggregates all lines on the calling goroutine and returns the number of lines read.
+// Like parseParallel, it aggregates every chunk of parseChunkLines lines on its own and merges the chunks
+// in order, so that sums of durations round the same way whatever the number of parse workers.
+func (a *LogAnalyzer) parseSequential(scanner *bufio.Scanner, parser domain.LogParser,
+	from, to time.Time, filterField, filterValue string) int {
 	lineCount := 0
+	chunk := domain.NewMetrics(nil)
+
+	byteParser, _ := parser.(domain.ByteParser)
 
 	for scanner.Scan() {
 		lineCount++
-		line := scanner.Text()
-		logRecord, err := ParseLogLine(line)
 
-		if err != nil {
-			fmt.Printf("Error parsing line: %s, Error: %v\n", line, err)
-			continue
-		}
+		if byteParser == nil {
+			a.processLine(chunk, parser, scanner.Text(), from, to, filterField, filterValue)
+		} else {
+			var logRecord domain.LogRecord
 
-		// Time range filter.
-		if !from.IsZero() && logRecord.Timestamp.Before(from) {
-			continue
+			if err := byteParser.ParseLogBytes(scanner.Bytes(), &logRecord); err != nil {
+				reportParseError(scanner.Text(), err)
+			} else {
+				a.processRecord(chunk, &logRecord, from, to, filterField, filterValue)
+			}
 		}
 
-		if !to.IsZero() && logRecord.Timestamp.After(to) {
-			continue
+		if lineCount%parseChunkLines == 0 {
+			a.Metrics.Merge(chunk)
+			chunk = domain.NewMetrics(nil)
 		}
+	}
+
+	a.Metrics.Merge(chunk)
+
+	return lineCount
+}
+
+// lineChunk is a chunk of consecutive lines handed to a parser goroutine, numbered in input order.
+type lineChunk struct {
+	seq   int
+	lines []string
+}
 
-		// Apply additional filters.
-		if filterField != "" && filterValue != "" {
-			if !matchesFilter(&logRecord, filterField, filterValue) {
-				continue
+// parsedChunk holds the metrics aggregated from a lineChunk.
+type parsedChunk struct {
+	seq     int
+	metrics *domain.Metrics
+}
+
+// parseParallel runs a pipeline: the calling goroutine splits the input into chunks of whole lines,
+// ParseWorkers goroutines parse, filter and aggregate every chunk into metrics of its own, and the
+// metrics of the chunks are merged in input order. Merging in a fixed order keeps order-sensitive
+// metrics such as StartDate and EndDate correct and makes the result identical to that of
+// parseSequential, down to the rounding of sums of durations. Returns the number of lines read.
+func (a *LogAnalyzer) parseParallel(scanner *bufio.Scanner, parser domain.LogParser,
+	from, to time.Time, filterField, filterValue string) int {
+	chunks := make(chan lineChunk, a.ParseWorkers)
+	parsed := make(chan parsedChunk, a.ParseWorkers)
+	// Bounds the chunks read but not merged yet, which pile up while an earlier chunk is being parsed.
+	inFlight := make(chan struct{}, a.ParseWorkers*4)
+
+	var wg sync.WaitGroup
+
+	for range a.ParseWorkers {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for chunk := range chunks {
+				metrics := domain.NewMetrics(nil)
+
+				for _, line := range chunk.lines {
+					a.processLine(metrics, parser, line, from, to, filterField, filterValue)
+				}
+
+				parsed <- parsedChunk{seq: chunk.seq, metrics: metrics}
 			}
+		}()
+	}
+
+	merged := make(chan struct{})
+
+	go func() {
+		a.mergeInOrder(parsed, inFlight)
+		close(merged)
+	}()
+
+	lineCount := 0
+	chunk := lineChunk{lines: make([]string, 0, parseChunkLines)}
+
+	for scanner.Scan() {
+		lineCount++
+
+		chunk.lines = append(chunk.lines, scanner.Text())
+		if len(chunk.lines) == parseChunkLines {
+			inFlight <- struct{}{}
+			chunks <- chunk
+			chunk = lineChunk{seq: chunk.seq + 1, lines: make([]string, 0, parseChunkLines)}
 		}
+	}
 
-		a.updateMetrics(&logRecord)
+	if len(chunk.lines) > 0 {
+		inFlight <- struct{}{}
+		chunks <- chunk
 	}
 
-	fmt.Printf("Processed %d lines from log source\n", lineCount)
-	fmt.Println()
+	close(chunks)
+	wg.Wait()
+	close(parsed)
+	<-merged
 
-	return scanner.Err()
+	return lineCount
+}
+
+// mergeInOrder merges the metrics of parsed chunks into the analyzer metrics in the order of their
+// sequence numbers, holding back the chunks that arrive early, and frees a slot of inFlight for
+// every merged chunk.
+func (a *LogAnalyzer) mergeInOrder(parsed <-chan parsedChunk, inFlight <-chan struct{}) {
+	pending := make(map[int]*domain.Metrics)
+	next := 0
+
+	for chunk := range parsed {
+		pending[chunk.seq] = chunk.metrics
+
+		for metrics, ok := pending[next]; ok; metrics, ok = pending[next] {
+			a.Metrics.Merge(metrics)
+			delete(pending, next)
+			next++
+
+			<-inFlight
+		}
+	}
+}
+
+// processLine parses a single line and adds it to the metrics if it passes the filters.
+func (a *LogAnalyzer) processLine(metrics *domain.Metrics, parser domain.LogParser, line string,
+	from, to time.Time, filterField, filterValue string) {
+	logRecord, err := parser.ParseLogLine(line)
+	if err != nil {
+		reportParseError(line, err)
+		return
+	}
+
+	a.processRecord(metrics, &logRecord, from, to, filterField, filterValue)
+}
+
+// reportParseError prints the error of a line that failed to parse, unless the line is meant to be skipped.
+func reportParseError(line string, err error) {
+	if !errors.Is(err, domain.ErrSkipLine) {
+		fmt.Printf("Error parsing line: %s, Error: %v\n", line, err)
+	}
+}
+
+// processRecord adds a parsed record to the metrics if it passes the filters.
+func (a *LogAnalyzer) processRecord(metrics *domain.Metrics, logRecord *domain.LogRecord,
+	from, to time.Time, filterField, filterValue string) {
+	// Time range filter.
+	if !from.IsZero() && logRecord.Timestamp.Before(from) {
+		return
+	}
+
+	if !to.IsZero() && logRecord.Timestamp.After(to) {
+		return
+	}
+
+	// Apply additional filters.
+	if filterField != "" && filterValue != "" {
+		if !matchesFilter(logRecord, filterField, filterValue) {
+			return
+		}
+	}
+
+	a.updateMetrics(metrics, logRecord)
 }
@@ -0,0 +1,125 @@
+package application_test
+
+import (
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLogAnalyzer_ParallelParsingMatchesSequential(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "access.log")
+	writeRandomLog(t, path, 10000, 7)
+
+	// The same log with request times, whose float sums depend on the order they are added in.
+	timedPath := filepath.Join(dir, "timed.log")
+	writeTimedLog(t, path, timedPath, 7)
+
+	tests := []struct {
+		name      string
+		path      string
+		newParser func() domain.LogParser
+	}{
+		{
+			name:      "Combined",
+			path:      path,
+			newParser: func() domain.LogParser { return &application.CombinedParser{} },
+		},
+		{
+			name: "NGINX with request time",
+			path: timedPath,
+			newParser: func() domain.LogParser {
+				parser, err := application.NewNginxParser(application.NginxCombinedFormat + " $request_time")
+				require.NoError(t, err)
+
+				return parser
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyze := func(parseWorkers int, filterField, filterValue string) *domain.Metrics {
+				analyzer := application.NewLogAnalyzer([]string{tt.path}, tt.newParser())
+				analyzer.ParseWorkers = parseWorkers
+
+				err := analyzer.AnalyzeLogs(time.Date(2021, time.December, 12, 1, 0, 0, 0, time.UTC), time.Time{}, filterField, filterValue)
+				assert.NoError(t, err, "Expected no error, but got one.")
+
+				return analyzer.Metrics
+			}
+
+			for _, filter := range [][2]string{{"", ""}, {"status", "4*"}} {
+				sequential := analyze(1, filter[0], filter[1])
+				assert.NotZero(t, sequential.TotalRequests, "Sequential run found no requests.")
+
+				for _, parseWorkers := range []int{2, 3, 8} {
+					parallel := analyze(parseWorkers, filter[0], filter[1])
+					assert.Equal(t, sequential, parallel, "Metrics with %d parse workers differ from a sequential run.", parseWorkers)
+				}
+			}
+		})
+	}
+}
+
+// writeTimedLog copies the log at src to dst, appending a random request time to every line.
+func writeTimedLog(tb testing.TB, src, dst string, seed int64) {
+	tb.Helper()
+
+	data, err := os.ReadFile(src)
+	require.NoError(tb, err)
+
+	rng := rand.New(rand.NewSource(seed))
+
+	var sb strings.Builder
+
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if line != "" {
+			fmt.Fprintf(&sb, "%s %.3f\n", strings.TrimSuffix(line, "\n"), rng.Float64())
+		}
+	}
+
+	require.NoError(tb, os.WriteFile(dst, []byte(sb.String()), 0o600))
+}
+
+func BenchmarkLogAnalyzer_ParseWorkers(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "access.log")
+	writeRandomLog(b, path, 200000, 1)
+
+	info, err := os.Stat(path)
+	assert.NoError(b, err)
+
+	// Keep the progress output of the analyzer out of the benchmark results.
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	assert.NoError(b, err)
+
+	stdout := os.Stdout
+	os.Stdout = devNull
+
+	defer func() {
+		os.Stdout = stdout
+		devNull.Close()
+	}()
+
+	for _, parseWorkers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", parseWorkers), func(b *testing.B) {
+			b.SetBytes(info.Size())
+
+			for i := 0; i < b.N; i++ {
+				analyzer := application.NewLogAnalyzer([]string{path}, &application.CombinedParser{})
+				analyzer.ParseWorkers = parseWorkers
+
+				_ = analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", "")
+			}
+		})
+	}
+}
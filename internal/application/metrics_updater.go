@@ -1,15 +1,13 @@
 package application
 
 import (
-	"sort"
+	"math"
 
 	"github.com/abakunov/log-analyzer/internal/domain"
 )
 
 // updateMetrics updates the metrics based on the log record.
-func (a *LogAnalyzer) updateMetrics(logRecord *domain.LogRecord) {
-	metrics := a.Metrics
-
+func (a *LogAnalyzer) updateMetrics(metrics *domain.Metrics, logRecord *domain.LogRecord) {
 	metrics.TotalRequests++
 
 	// Update StartDate and EndDate.
@@ -24,27 +22,67 @@ func (a *LogAnalyzer) updateMetrics(logRecord *domain.LogRecord) {
 	metrics.TotalRespSize += logRecord.ResponseSize
 	metrics.AverageRespSize = float64(metrics.TotalRespSize) / float64(metrics.TotalRequests)
 
-	metrics.ResponseSizes = append(metrics.ResponseSizes, logRecord.ResponseSize)
-	metrics.Percentile95 = a.CalculatePercentile(metrics.ResponseSizes, 95)
+	metrics.ResponseSizes.Add(float64(logRecord.ResponseSize))
+
+	a.updateBreakdowns(metrics, logRecord)
 
-	metrics.Resources[logRecord.URL]++
 	metrics.StatusCodes[logRecord.StatusCode]++
+
+	if logRecord.RemoteUser != "" {
+		metrics.Users[logRecord.RemoteUser]++
+	}
+
 	metrics.UniqueIPs[logRecord.IP] = struct{}{}
+
+	for name, seconds := range logRecord.Timings {
+		stats, ok := metrics.Timings[name]
+		if !ok {
+			stats = &domain.TimingStats{}
+			metrics.Timings[name] = stats
+		}
+
+		stats.Count++
+		stats.Total += seconds
+		stats.Max = math.Max(stats.Max, seconds)
+	}
+
+	updateLatency(metrics, logRecord)
+
+	if a.BucketWidth > 0 {
+		metrics.Timeline.SetWidth(a.BucketWidth)
+	}
+
+	metrics.Timeline.Add(logRecord.Timestamp, logRecord.IP, logRecord.StatusCode, logRecord.ResponseSize)
 }
 
-// CalculatePercentile calculates the value of the specified percentile.
-func (a *LogAnalyzer) CalculatePercentile(values []int, percentile float64) int {
-	if len(values) == 0 {
-		return 0
+// updateBreakdowns records the request under its resource, the normalized URL, and under its client IP.
+func (a *LogAnalyzer) updateBreakdowns(metrics *domain.Metrics, logRecord *domain.LogRecord) {
+	resource := a.URLNormalizer.Normalize(logRecord.URL)
+	stats, ok := metrics.ResourceStats[resource]
+	if !ok {
+		stats = domain.NewResourceStats()
+		metrics.ResourceStats[resource] = stats
 	}
 
-	sort.Ints(values)
+	stats.Add(logRecord)
 
-	index := int(float64(len(values)) * percentile / 100)
+	client, ok := metrics.Clients[logRecord.IP]
+	if !ok {
+		client = domain.NewClientStats()
+		metrics.Clients[logRecord.IP] = client
+	}
+
+	client.Add(resource, logRecord.StatusCode, logRecord.ResponseSize)
+}
 
-	if index >= len(values) {
-		index = len(values) - 1
+// updateLatency records the latency of the request. Lines without timing are only counted, so
+// that they do not pull the averages and percentiles towards zero.
+func updateLatency(metrics *domain.Metrics, logRecord *domain.LogRecord) {
+	seconds, ok := logRecord.Latency()
+	if !ok {
+		metrics.UntimedRequests++
+		return
 	}
 
-	return values[index]
+	metrics.Latency.Add(seconds)
 }
@@ -0,0 +1,248 @@
+package application
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+// NginxCombinedFormat is the log_format string of the predefined NGINX "combined" format.
+const NginxCombinedFormat = `$remote_addr - $remote_user [$time_local] ` +
+	`"$request" $status $body_bytes_sent "$http_referer" "$http_user_agent"`
+
+var nginxVariablePattern = regexp.MustCompile(`\$(?:\{([A-Za-z0-9_]+)\}|([A-Za-z0-9_]+))`)
+
+// NginxParser parses lines written by NGINX with a custom log_format directive.
+// Variables that have no counterpart in domain.LogRecord are stored in LogRecord.Extra.
+type NginxParser struct {
+	format    string
+	variables []string
+	re        *regexp.Regexp
+}
+
+// NewNginxParser compiles an NGINX log_format string into a parser.
+func NewNginxParser(format string) (*NginxParser, error) {
+	if strings.TrimSpace(format) == "" {
+		return nil, fmt.Errorf("empty nginx log_format")
+	}
+
+	type token struct {
+		literal  string
+		variable string
+	}
+
+	var tokens []token
+
+	last := 0
+
+	for _, loc := range nginxVariablePattern.FindAllStringSubmatchIndex(format, -1) {
+		if loc[0] > last {
+			tokens = append(tokens, token{literal: format[last:loc[0]]})
+		}
+
+		var name string
+		if loc[2] >= 0 {
+			name = format[loc[2]:loc[3]] // ${name} form.
+		} else {
+			name = format[loc[4]:loc[5]]
+		}
+
+		tokens = append(tokens, token{variable: name})
+		last = loc[1]
+	}
+
+	if last < len(format) {
+		tokens = append(tokens, token{literal: format[last:]})
+	}
+
+	var (
+		sb        strings.Builder
+		variables []string
+	)
+
+	sb.WriteString("^")
+
+	for i, tok := range tokens {
+		if tok.variable == "" {
+			sb.WriteString(regexp.QuoteMeta(tok.literal))
+			continue
+		}
+
+		var next string
+		if i+1 < len(tokens) {
+			next = tokens[i+1].literal
+		}
+
+		fmt.Fprintf(&sb, "(%s)", nginxVariableRegexp(next, i == len(tokens)-1))
+
+		variables = append(variables, tok.variable)
+	}
+
+	sb.WriteString("$")
+
+	if len(variables) == 0 {
+		return nil, fmt.Errorf("nginx log_format %q contains no variables", format)
+	}
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to compile nginx log_format %q: %w", format, err)
+	}
+
+	return &NginxParser{format: format, variables: variables, re: re}, nil
+}
+
+// nginxVariableRegexp returns the pattern matching a variable value that is followed by the given literal.
+func nginxVariableRegexp(nextLiteral string, isLast bool) string {
+	switch {
+	case isLast:
+		return ".*"
+	case nextLiteral == "":
+		return `\S*`
+	case nextLiteral[0] == '"':
+		// Values inside quotes may contain escaped quotes (escape=json or Apache style).
+		return `(?:[^"\\]|\\.)*`
+	default:
+		return "[^" + regexp.QuoteMeta(nextLiteral[:1]) + "]*"
+	}
+}
+
+// Format returns the log_format string the parser was compiled from.
+func (p *NginxParser) Format() string {
+	return p.format
+}
+
+// ParseLogLine implements domain.LogParser.
+func (p *NginxParser) ParseLogLine(line string) (domain.LogRecord, error) {
+	var log domain.LogRecord
+
+	matches := p.re.FindStringSubmatch(line)
+	if len(matches) != len(p.variables)+1 {
+		return log, fmt.Errorf("failed to parse line: %s", line)
+	}
+
+	for i, name := range p.variables {
+		if err := applyNginxVariable(&log, name, matches[i+1]); err != nil {
+			return domain.LogRecord{}, err
+		}
+	}
+
+	return log, nil
+}
+
+// applyNginxVariable stores the value of a single NGINX variable in the log record.
+func applyNginxVariable(log *domain.LogRecord, name, value string) error {
+	switch name {
+	case "remote_addr":
+		log.IP = value
+	case "remote_user":
+		log.RemoteUser = optionalField(value)
+	case "time_local", "time_iso8601", "msec":
+		timestamp, err := parseNginxTime(name, value)
+		if err != nil {
+			return fmt.Errorf("failed to parse time: %v", err)
+		}
+
+		log.Timestamp = timestamp
+	case "request":
+		parts := strings.Fields(value)
+		if len(parts) != 3 {
+			return fmt.Errorf("failed to parse request: %s", value)
+		}
+
+		log.Method, log.URL, log.Protocol = parts[0], parts[1], parts[2]
+	case "request_method":
+		log.Method = value
+	case "request_uri":
+		log.URL = value
+	case "uri":
+		if log.URL == "" {
+			log.URL = value
+		}
+	case "server_protocol":
+		log.Protocol = value
+	case "status":
+		statusCode, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse status code: %v", err)
+		}
+
+		log.StatusCode = statusCode
+	case "body_bytes_sent":
+		size, err := parseSize(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse response size: %v", err)
+		}
+
+		log.ResponseSize = size
+	case "http_referer":
+		log.Referer = value
+	case "http_user_agent":
+		log.UserAgent = value
+	case "request_time":
+		log.RequestTime, log.HasRequestTime = parseSeconds(value)
+	case "upstream_response_time":
+		log.UpstreamTime, log.HasUpstreamTime = parseUpstreamTime(value)
+	default:
+		if log.Extra == nil {
+			log.Extra = make(map[string]string)
+		}
+
+		log.Extra[name] = value
+	}
+
+	return nil
+}
+
+// parseNginxTime parses the value of one of the NGINX time variables $time_local, $time_iso8601 and $msec.
+func parseNginxTime(name, value string) (time.Time, error) {
+	switch name {
+	case "time_local":
+		return parseCombinedTime(value)
+	case "time_iso8601":
+		timestamp, err := time.Parse(time.RFC3339, value)
+		return timestamp.UTC(), err
+	default:
+		seconds, err := strconv.ParseFloat(value, 64)
+		return time.UnixMilli(int64(seconds * 1000)).UTC(), err
+	}
+}
+
+// parseSeconds parses a duration in seconds such as "0.125", reporting false for "-" and other invalid values.
+func parseSeconds(value string) (float64, bool) {
+	seconds, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+	if err != nil || seconds < 0 {
+		return 0, false
+	}
+
+	return seconds, true
+}
+
+// parseUpstreamTime parses $upstream_response_time, which holds one time per upstream server tried,
+// separated by commas, and by colons across internal redirects, such as "0.010, 0.120 : 0.030".
+// The times are summed; servers logged as "-" are skipped, and a value without any time reports false.
+func parseUpstreamTime(value string) (float64, bool) {
+	total, found := 0.0, false
+
+	for _, part := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ':' }) {
+		if seconds, ok := parseSeconds(part); ok {
+			total += seconds
+			found = true
+		}
+	}
+
+	return total, found
+}
+
+// parseSize parses a byte count where "-" means zero.
+func parseSize(value string) (int, error) {
+	if value == "-" {
+		return 0, nil
+	}
+
+	return strconv.Atoi(value)
+}
@@ -0,0 +1,139 @@
+package application_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNginxParser_ParseLogLine(t *testing.T) {
+	timedFormat := `$remote_addr - $remote_user [$time_local] "$request" $status $body_bytes_sent ` +
+		`"$http_referer" "$http_user_agent" "$http_x_forwarded_for" $host rt=$request_time urt=$upstream_response_time`
+
+	tests := []struct {
+		name      string
+		format    string
+		logLine   string
+		expected  domain.LogRecord
+		expectErr bool
+	}{
+		{
+			name:    "Predefined combined format",
+			format:  application.NginxCombinedFormat,
+			logLine: `127.0.0.1 - - [12/Dec/2021:19:01:02 +0000] "GET /index.html HTTP/1.1" 200 1024 "http://example.com" "Mozilla/5.0"`,
+			expected: domain.LogRecord{
+				IP:           "127.0.0.1",
+				Timestamp:    time.Date(2021, time.December, 12, 19, 1, 2, 0, time.UTC),
+				Method:       "GET",
+				URL:          "/index.html",
+				Protocol:     "HTTP/1.1",
+				StatusCode:   200,
+				ResponseSize: 1024,
+				Referer:      "http://example.com",
+				UserAgent:    "Mozilla/5.0",
+			},
+			expectErr: false,
+		},
+		{
+			name:   "Custom format with timings and unknown variables",
+			format: timedFormat,
+			logLine: `10.0.0.2 - alice [12/Dec/2021:19:01:02 +0300] "POST /api/v1/items HTTP/2.0" 201 - "-" ` +
+				`"curl/8.0" "203.0.113.7, 10.0.0.1" api.example.com rt=0.125 urt=0.120`,
+			expected: domain.LogRecord{
+				IP:           "10.0.0.2",
+				Timestamp:    time.Date(2021, time.December, 12, 16, 1, 2, 0, time.UTC),
+				Method:       "POST",
+				URL:          "/api/v1/items",
+				Protocol:     "HTTP/2.0",
+				StatusCode:   201,
+				ResponseSize: 0,
+				Referer:      "-",
+				UserAgent:    "curl/8.0",
+				RemoteUser:   "alice",
+				Extra: map[string]string{
+					"http_x_forwarded_for": "203.0.113.7, 10.0.0.1",
+					"host":                 "api.example.com",
+				},
+				RequestTime:     0.125,
+				UpstreamTime:    0.120,
+				HasRequestTime:  true,
+				HasUpstreamTime: true,
+			},
+			expectErr: false,
+		},
+		{
+			name:    "ISO8601 time and braced variables",
+			format:  `${remote_addr} $time_iso8601 $request_method $request_uri $status`,
+			logLine: `::1 2021-12-12T19:01:02+00:00 GET /health 204`,
+			expected: domain.LogRecord{
+				IP:         "::1",
+				Timestamp:  time.Date(2021, time.December, 12, 19, 1, 2, 0, time.UTC),
+				Method:     "GET",
+				URL:        "/health",
+				StatusCode: 204,
+			},
+			expectErr: false,
+		},
+		{
+			name:    "Upstream times of several servers and internal redirects",
+			format:  `$remote_addr $status $request_time $upstream_response_time`,
+			logLine: `10.0.0.3 502 - 0.010, - : 0.030`,
+			expected: domain.LogRecord{
+				IP:              "10.0.0.3",
+				StatusCode:      502,
+				UpstreamTime:    0.04,
+				HasUpstreamTime: true,
+			},
+			expectErr: false,
+		},
+		{
+			name:    "No timing logged",
+			format:  `$remote_addr $status rt=$request_time urt=$upstream_response_time`,
+			logLine: `10.0.0.4 499 rt=- urt=-`,
+			expected: domain.LogRecord{
+				IP:         "10.0.0.4",
+				StatusCode: 499,
+			},
+			expectErr: false,
+		},
+		{
+			name:      "Line does not match the format",
+			format:    timedFormat,
+			logLine:   `Invalid log line format`,
+			expectErr: true,
+		},
+		{
+			name:      "Invalid status code",
+			format:    `$remote_addr $status`,
+			logLine:   `127.0.0.1 abc`,
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parser, err := application.NewNginxParser(tt.format)
+			assert.NoError(t, err)
+
+			record, err := parser.ParseLogLine(tt.logLine)
+
+			if tt.expectErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, record)
+			}
+		})
+	}
+}
+
+func TestNewNginxParser_InvalidFormat(t *testing.T) {
+	_, err := application.NewNginxParser("")
+	assert.Error(t, err, "Empty format should be rejected.")
+
+	_, err = application.NewNginxParser("no variables here")
+	assert.Error(t, err, "Format without variables should be rejected.")
+}
@@ -0,0 +1,82 @@
+package application
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+// ParserFactory creates a new instance of a log parser.
+type ParserFactory func() domain.LogParser
+
+// ParserRegistry maps log format names to the factories of their parsers.
+type ParserRegistry map[string]ParserFactory
+
+var (
+	parserRegistryMu sync.RWMutex
+	parserRegistry   = ParserRegistry{
+		"combined":   func() domain.LogParser { return &CombinedParser{} },
+		"common":     func() domain.LogParser { return &CommonParser{} },
+		"json":       func() domain.LogParser { return NewJSONParser(nil) },
+		"alb":        func() domain.LogParser { return &ALBParser{} },
+		"elb":        func() domain.LogParser { return &ELBParser{} },
+		"cloudfront": func() domain.LogParser { return &CloudFrontParser{} },
+	}
+)
+
+// RegisterParser makes a log format available under the given name.
+// Registering a name twice replaces the previous factory.
+func RegisterParser(name string, factory ParserFactory) {
+	parserRegistryMu.Lock()
+	defer parserRegistryMu.Unlock()
+
+	parserRegistry[name] = factory
+}
+
+// RegisteredParsers returns a copy of the registered log formats, which can be changed for a single
+// analyzer, for example to map the fields of the "json" format, without affecting other users.
+func RegisteredParsers() ParserRegistry {
+	parserRegistryMu.RLock()
+	defer parserRegistryMu.RUnlock()
+
+	registry := make(ParserRegistry, len(parserRegistry))
+	for name, factory := range parserRegistry {
+		registry[name] = factory
+	}
+
+	return registry
+}
+
+// NewParser returns a parser for the log format registered under the given name.
+func NewParser(name string) (domain.LogParser, error) {
+	return RegisteredParsers().NewParser(name)
+}
+
+// ParserNames returns the names of all registered log formats in sorted order.
+func ParserNames() []string {
+	return RegisteredParsers().Names()
+}
+
+// NewParser returns a parser for the log format with the given name.
+func (r ParserRegistry) NewParser(name string) (domain.LogParser, error) {
+	factory, ok := r[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown log format %q (available: %v)", name, r.Names())
+	}
+
+	return factory(), nil
+}
+
+// Names returns the names of the log formats in sorted order.
+func (r ParserRegistry) Names() []string {
+	names := make([]string, 0, len(r))
+	for name := range r {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
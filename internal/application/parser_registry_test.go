@@ -0,0 +1,57 @@
+package application_test
+
+import (
+	"testing"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+type stubParser struct{}
+
+func (p *stubParser) ParseLogLine(_ string) (domain.LogRecord, error) {
+	return domain.LogRecord{IP: "10.0.0.1", StatusCode: 200}, nil
+}
+
+func TestNewParser(t *testing.T) {
+	application.RegisterParser("stub", func() domain.LogParser { return &stubParser{} })
+
+	testCases := []struct {
+		name      string
+		format    string
+		expectErr bool
+	}{
+		{
+			name:      "Built-in combined format",
+			format:    "combined",
+			expectErr: false,
+		},
+		{
+			name:      "Registered custom format",
+			format:    "stub",
+			expectErr: false,
+		},
+		{
+			name:      "Unknown format",
+			format:    "does-not-exist",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			parser, err := application.NewParser(tc.format)
+
+			if tc.expectErr {
+				assert.Error(t, err, "Expected an error, but got none.")
+				assert.Nil(t, parser, "Parser should be nil on error.")
+			} else {
+				assert.NoError(t, err, "Did not expect an error, but got one.")
+				assert.NotNil(t, parser, "Parser should not be nil.")
+			}
+		})
+	}
+
+	assert.Contains(t, application.ParserNames(), "stub", "Registered format should be listed.")
+}
@@ -0,0 +1,258 @@
+package application
+
+import (
+	"cmp"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+// fingerprintBytes is how much decompressed content identifies a local file when looking for duplicates.
+const fingerprintBytes = 64 * 1024
+
+// rotatedNamePattern splits the name of a file rotated by logrotate into the name of the log, the
+// rotation number (access.log.1) or date (access.log-20241017, access.log.2024-10-17) and the
+// compression extension. A compressed file without a rotation suffix, such as access.log.gz, is a
+// log of its own.
+var rotatedNamePattern = regexp.MustCompile(
+	`^(.+?)(?:(?:\.(\d+)|[.-](\d{8}|\d{4}-\d{2}-\d{2})(?:[.-]\d+)?)(\.(?:gz|bz2|xz|zst|zip))?)?$`)
+
+// rotatedName is a source name split into its logrotate family and its age within the family.
+type rotatedName struct {
+	name       string
+	family     string // Directory and name of the log, the same for all its rotated files.
+	date       string // Rotation date of date-stamped files, which are older than numbered ones.
+	number     int    // Rotation number, higher for older files; 0 for the current log.
+	compressed bool   // Whether the name has a compression extension.
+}
+
+// parseRotatedName splits a source name into its logrotate family and age.
+func parseRotatedName(source string) rotatedName {
+	dir, name := "", source
+	if i := strings.LastIndexAny(source, `/\`); i >= 0 {
+		dir, name = source[:i+1], source[i+1:]
+	}
+
+	match := rotatedNamePattern.FindStringSubmatch(name)
+	if match == nil {
+		return rotatedName{name: source, family: source}
+	}
+
+	number, _ := strconv.Atoi(match[2])
+
+	return rotatedName{
+		name:       source,
+		family:     dir + match[1],
+		date:       strings.ReplaceAll(match[3], "-", ""),
+		number:     number,
+		compressed: match[4] != "",
+	}
+}
+
+// compareAge orders the files of a family from the oldest to the newest: date-stamped files by
+// date, then numbered files from the highest number, then the current log. Files of the same age,
+// such as access.log.1 and access.log.1.gz, are ordered uncompressed first, then by name.
+func (n rotatedName) compareAge(other rotatedName) int {
+	if c := cmp.Compare(n.rank(), other.rank()); c != 0 {
+		return c
+	}
+
+	if c := strings.Compare(n.date, other.date); c != 0 {
+		return c
+	}
+
+	if c := cmp.Compare(other.number, n.number); c != 0 {
+		return c
+	}
+
+	if n.compressed != other.compressed {
+		if n.compressed {
+			return 1
+		}
+
+		return -1
+	}
+
+	return strings.Compare(n.name, other.name)
+}
+
+// rank orders the kinds of files in a family: date-stamped, numbered, then the current log.
+func (n rotatedName) rank() int {
+	switch {
+	case n.date != "":
+		return 0
+	case n.number > 0:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// OrderRotated groups the rotated files of each log, such as access.log, access.log.1 and
+// access.log.2.gz, at the position of the first of them and orders each group from the oldest
+// file to the newest, so that records are read in time order. Other sources keep their order.
+func OrderRotated(sources []string) []string {
+	names := make([]rotatedName, len(sources))
+	families := make(map[string][]int)
+
+	for i, source := range sources {
+		names[i] = parseRotatedName(source)
+		if source != StdinPath {
+			families[names[i].family] = append(families[names[i].family], i)
+		}
+	}
+
+	ordered := make([]string, 0, len(sources))
+
+	for i, source := range sources {
+		if source == StdinPath {
+			ordered = append(ordered, source)
+			continue
+		}
+
+		members, ok := families[names[i].family]
+		if !ok {
+			continue // Already added with the first file of its family.
+		}
+
+		slices.SortStableFunc(members, func(a, b int) int { return names[a].compareAge(names[b]) })
+
+		for _, member := range members {
+			ordered = append(ordered, sources[member])
+		}
+
+		delete(families, names[i].family)
+	}
+
+	return ordered
+}
+
+// copyKey groups the sources that may be copies of each other: files of the same logrotate family
+// and age, such as access.log.1 and access.log.1.gz, that start with the same bytes.
+type copyKey struct {
+	family string
+	date   string
+	number int
+	prefix string
+}
+
+// dedupeSources leaves out local files whose content is identical to that of an earlier file of
+// the same logrotate family and age, such as access.log.1 next to access.log.1.gz, and removes them
+// from Metrics.FileNames. Files are compared by the hash of their decompressed content, which is
+// only computed for files that start with the same bytes. Identical files of different logs or
+// ages are all analyzed.
+func (a *LogAnalyzer) dedupeSources(sources []string) []string {
+	kept := make([]string, 0, len(sources))
+	candidates := make(map[copyKey][]string)
+	hashes := make(map[string]string)
+
+	for _, source := range sources {
+		if source == StdinPath || IsURL(source) || IsS3(source) {
+			kept = append(kept, source)
+			continue
+		}
+
+		prefix, _, err := contentHash(source, fingerprintBytes)
+		if err != nil || prefix == "" {
+			kept = append(kept, source)
+			continue
+		}
+
+		name := parseRotatedName(source)
+		key := copyKey{family: name.family, date: name.date, number: name.number, prefix: prefix}
+		duplicate := ""
+
+		for _, other := range candidates[key] {
+			if sameContent(source, other, hashes) {
+				duplicate = other
+				break
+			}
+
+			fmt.Printf("Warning: %s and %s start with the same lines; records may be counted twice\n", other, source)
+		}
+
+		if duplicate != "" {
+			fmt.Printf("Skipping %s: same content as %s\n", source, duplicate)
+			a.replaceFileName(source, nil)
+
+			continue
+		}
+
+		candidates[key] = append(candidates[key], source)
+		kept = append(kept, source)
+	}
+
+	return kept
+}
+
+// sameContent reports whether two local files have the same decompressed content, caching the hashes of whole files.
+func sameContent(a, b string, hashes map[string]string) bool {
+	for _, path := range []string{a, b} {
+		if _, ok := hashes[path]; !ok {
+			hash, _, err := contentHash(path, -1)
+			if err != nil {
+				return false
+			}
+
+			hashes[path] = hash
+		}
+	}
+
+	return hashes[a] == hashes[b]
+}
+
+// contentHash returns the SHA-256 of the first limit bytes of the decompressed content of a local
+// file, or of all of it for a negative limit, and the number of bytes hashed. It returns an empty
+// string for an empty file.
+func contentHash(path string, limit int64) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, err
+	}
+	defer file.Close()
+
+	decompressed, err := decompress(file)
+	if err != nil {
+		return "", 0, err
+	}
+	defer decompressed.Close()
+
+	var reader io.Reader = decompressed
+	if limit >= 0 {
+		reader = io.LimitReader(decompressed, limit)
+	}
+
+	hash := sha256.New()
+
+	n, err := io.Copy(hash, reader)
+	if err != nil || n == 0 {
+		return "", 0, err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), n, nil
+}
+
+// warnOverlaps warns about consecutive files of a logrotate family whose records overlap in time,
+// which happens when the same records were rotated into two files.
+func warnOverlaps(sources []string, partials []*domain.Metrics) {
+	for i := 1; i < len(sources); i++ {
+		previous, current := partials[i-1], partials[i]
+
+		if parseRotatedName(sources[i-1]).family != parseRotatedName(sources[i]).family ||
+			previous.EndDate.IsZero() || current.StartDate.IsZero() || !current.StartDate.Before(previous.EndDate) {
+			continue
+		}
+
+		fmt.Printf("Warning: %s (%s - %s) overlaps %s (%s - %s); records may be counted twice\n",
+			sources[i], current.StartDate.Format(combinedTimeLayout), current.EndDate.Format(combinedTimeLayout),
+			sources[i-1], previous.StartDate.Format(combinedTimeLayout), previous.EndDate.Format(combinedTimeLayout))
+	}
+}
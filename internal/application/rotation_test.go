@@ -0,0 +1,135 @@
+package application_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderRotated(t *testing.T) {
+	tests := []struct {
+		name     string
+		sources  []string
+		expected []string
+	}{
+		{
+			name:     "Numbered rotation",
+			sources:  []string{"logs/access.log", "logs/access.log.1", "logs/access.log.10.gz", "logs/access.log.2.gz"},
+			expected: []string{"logs/access.log.10.gz", "logs/access.log.2.gz", "logs/access.log.1", "logs/access.log"},
+		},
+		{
+			name:     "Date extension",
+			sources:  []string{"access.log", "access.log-20241017.gz", "access.log-20241016"},
+			expected: []string{"access.log-20241016", "access.log-20241017.gz", "access.log"},
+		},
+		{
+			name: "Families keep their positions",
+			sources: []string{
+				"a/error.log", "a/access.log", "b/access.log.1", "a/access.log.1", "-", "a/error.log.1", "b/access.log",
+			},
+			expected: []string{
+				"a/error.log.1", "a/error.log", "a/access.log.1", "a/access.log", "b/access.log.1", "b/access.log", "-",
+			},
+		},
+		{
+			name:     "Same age",
+			sources:  []string{"access.log.1.gz", "access.log.1.zst", "access.log.1"},
+			expected: []string{"access.log.1", "access.log.1.gz", "access.log.1.zst"},
+		},
+		{
+			name:     "Compressed log without a rotation suffix",
+			sources:  []string{"access.log.gz", "access.log", "access.log.1"},
+			expected: []string{"access.log.gz", "access.log.1", "access.log"},
+		},
+		{
+			name:     "URLs",
+			sources:  []string{"https://logs.example.com/access.log", "https://logs.example.com/access.log.1.gz"},
+			expected: []string{"https://logs.example.com/access.log.1.gz", "https://logs.example.com/access.log"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, application.OrderRotated(tt.sources))
+		})
+	}
+}
+
+func TestLogAnalyzer_RotatedSources(t *testing.T) {
+	line := func(clock string) string {
+		return strings.Replace(tailLine, "19:01:02", clock, 1)
+	}
+
+	var compressed bytes.Buffer
+
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte(line("10:00:00") + line("10:00:30")))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	dir := t.TempDir()
+	files := map[string]string{
+		"access.log":      line("11:00:00"),
+		"access.log.1":    line("10:00:00") + line("10:00:30"),
+		"access.log.1.gz": compressed.String(),
+		"access.log.2":    line("09:00:00") + line("10:00:10"),
+	}
+
+	var paths []string
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		paths = append(paths, path)
+	}
+
+	analyzer := application.NewLogAnalyzer(application.OrderRotated(paths), &application.CombinedParser{})
+
+	output := captureStdout(t, func() {
+		require.NoError(t, analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", ""))
+	})
+
+	assert.Equal(t, 5, analyzer.Metrics.TotalRequests, "The compressed copy of access.log.1 should be skipped.")
+	assert.Equal(t, []string{
+		filepath.Join(dir, "access.log.2"), filepath.Join(dir, "access.log.1"), filepath.Join(dir, "access.log"),
+	}, analyzer.Metrics.FileNames)
+	assert.Contains(t, output,
+		"Skipping "+filepath.Join(dir, "access.log.1.gz")+": same content as "+filepath.Join(dir, "access.log.1"))
+	assert.Contains(t, output, "Warning: "+filepath.Join(dir, "access.log.1")+
+		" (12/Dec/2021:10:00:00 +0000 - 12/Dec/2021:10:00:30 +0000) overlaps "+filepath.Join(dir, "access.log.2"))
+}
+
+// captureStdout returns what the function prints to the standard output.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+
+	reader, writer, err := os.Pipe()
+	require.NoError(t, err)
+
+	stdout := os.Stdout
+	os.Stdout = writer
+
+	output := make(chan string)
+
+	go func() {
+		data, _ := io.ReadAll(reader)
+		output <- string(data)
+	}()
+
+	f()
+
+	os.Stdout = stdout
+	writer.Close()
+
+	return <-output
+}
@@ -0,0 +1,210 @@
+package application
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	// S3Scheme starts the paths of objects in S3-compatible object storage.
+	S3Scheme = "s3://"
+
+	// DefaultS3Region is the region used when none is configured.
+	DefaultS3Region = "us-east-1"
+)
+
+// S3Credentials are the AWS access keys used to sign requests. Requests are sent unsigned,
+// as for public buckets, if AccessKeyID is empty.
+type S3Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// S3Config configures access to S3-compatible object storage.
+type S3Config struct {
+	Endpoint    string // Base URL of the service, such as http://localhost:9000; AWS S3 of Region if empty.
+	Region      string // Region requests are signed for, DefaultS3Region if empty.
+	PathStyle   bool   // Address buckets as Endpoint/bucket instead of bucket.Endpoint, as MinIO requires; always for names with dots.
+	Credentials S3Credentials
+}
+
+// listBucketResult is the response of the ListObjectsV2 API.
+type listBucketResult struct {
+	Contents []struct {
+		Key string `xml:"Key"`
+	} `xml:"Contents"`
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+}
+
+// IsS3 checks if a given path names objects in S3-compatible storage.
+func IsS3(path string) bool {
+	return strings.HasPrefix(path, S3Scheme)
+}
+
+// expandRemote expands a URL or an S3 path into the sources it names.
+func (a *LogAnalyzer) expandRemote(path string) ([]string, error) {
+	if IsS3(path) {
+		return a.expandS3(path)
+	}
+
+	return a.expandURL(path)
+}
+
+// expandS3 turns an s3:// path into the paths of the objects it names. A key ending with a slash
+// names all objects under that prefix, a key with a glob pattern names the matching objects, and
+// any other key names a single object.
+func (a *LogAnalyzer) expandS3(s3Path string) ([]string, error) {
+	bucket, key, err := splitS3Path(s3Path)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix, match := key, func(string) bool { return true }
+
+	switch {
+	case key == "" || strings.HasSuffix(key, "/"):
+	case strings.ContainsAny(key, "*?["):
+		if _, err := path.Match(key, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern in %s: %w", s3Path, err)
+		}
+
+		prefix = key[:strings.IndexAny(key, "*?[")]
+		match = func(name string) bool {
+			matched, _ := path.Match(key, name)
+			return matched
+		}
+	default:
+		return []string{s3Path}, nil
+	}
+
+	fmt.Printf("Listing objects: %s\n", s3Path)
+
+	keys, err := a.listS3(bucket, prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", s3Path, err)
+	}
+
+	var paths []string
+
+	for _, name := range keys {
+		if match(name) && !strings.HasSuffix(name, "/") {
+			paths = append(paths, S3Scheme+bucket+"/"+name)
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("no objects match %s", s3Path)
+	}
+
+	return paths, nil
+}
+
+// listS3 returns the keys of all objects in the bucket that start with the prefix.
+func (a *LogAnalyzer) listS3(bucket, prefix string) ([]string, error) {
+	var keys []string
+
+	query := url.Values{"list-type": {"2"}, "prefix": {prefix}}
+
+	for {
+		body, err := a.HTTP.openRequest(a.S3.objectURL(bucket, "", query), a.S3.sign)
+		if err != nil {
+			return nil, err
+		}
+
+		var result listBucketResult
+
+		err = xml.NewDecoder(body).Decode(&result)
+		body.Close()
+
+		if err != nil {
+			return nil, fmt.Errorf("invalid object listing: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			keys = append(keys, object.Key)
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return keys, nil
+		}
+
+		query.Set("continuation-token", result.NextContinuationToken)
+	}
+}
+
+// processS3 streams a single object from S3-compatible storage.
+func (a *LogAnalyzer) processS3(s3Path string, from, to time.Time, filterField, filterValue string) error {
+	bucket, key, err := splitS3Path(s3Path)
+	if err != nil {
+		return err
+	}
+
+	body, err := a.HTTP.openRequest(a.S3.objectURL(bucket, key, nil), a.S3.sign)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	return a.processStream(body, s3Path, from, to, filterField, filterValue)
+}
+
+// splitS3Path splits an s3://bucket/key path into the bucket and the key.
+func splitS3Path(s3Path string) (bucket, key string, err error) {
+	bucket, key, _ = strings.Cut(strings.TrimPrefix(s3Path, S3Scheme), "/")
+	if bucket == "" {
+		return "", "", fmt.Errorf("invalid S3 path %s: missing bucket", s3Path)
+	}
+
+	return bucket, key, nil
+}
+
+// objectURL returns the URL of an object, or of the bucket itself for an empty key.
+func (c *S3Config) objectURL(bucket, key string, query url.Values) string {
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", c.region())
+	}
+
+	base, err := url.Parse(endpoint)
+	if err != nil || base.Host == "" {
+		base = &url.URL{Scheme: "https", Host: endpoint}
+	}
+
+	// A bucket name with dots does not match the wildcard certificate of virtual-hosted addresses.
+	objectPath := strings.TrimSuffix(base.Path, "/") + "/" + awsEscape(key, false)
+	if c.PathStyle || strings.Contains(bucket, ".") {
+		objectPath = strings.TrimSuffix(base.Path, "/") + "/" + awsEscape(bucket, true) + "/" + awsEscape(key, false)
+	} else {
+		base.Host = bucket + "." + base.Host
+	}
+
+	objectURL := base.Scheme + "://" + base.Host + objectPath
+	if len(query) > 0 {
+		objectURL += "?" + canonicalQuery(query)
+	}
+
+	return objectURL
+}
+
+// sign signs a request with the configured credentials, if there are any.
+func (c *S3Config) sign(req *http.Request) {
+	if c.Credentials.AccessKeyID != "" {
+		signV4(req, c.Credentials, c.region(), time.Now())
+	}
+}
+
+// region returns the configured region or DefaultS3Region.
+func (c *S3Config) region() string {
+	if c.Region == "" {
+		return DefaultS3Region
+	}
+
+	return c.Region
+}
@@ -0,0 +1,153 @@
+package application_test
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 serves the objects of a single bucket through the path-style ListObjectsV2 and GetObject
+// APIs, one key per listing page, and rejects requests that are not signed or that carry the headers
+// configured for URL sources.
+func fakeS3(t *testing.T, bucket string, objects map[string]string) *httptest.Server {
+	t.Helper()
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("X-Tenant") != "" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		key, ok := strings.CutPrefix(r.URL.Path, "/"+bucket+"/")
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if key != "" {
+			content, ok := objects[key]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			http.ServeContent(w, r, key, time.Time{}, strings.NewReader(content))
+
+			return
+		}
+
+		var keys []string
+
+		for name := range objects {
+			if strings.HasPrefix(name, r.URL.Query().Get("prefix")) {
+				keys = append(keys, name)
+			}
+		}
+
+		slices.Sort(keys)
+
+		page, _ := strconv.Atoi(r.URL.Query().Get("continuation-token"))
+
+		type object struct {
+			Key string
+		}
+
+		result := struct {
+			XMLName               xml.Name `xml:"ListBucketResult"`
+			Contents              []object
+			IsTruncated           bool
+			NextContinuationToken string `xml:",omitempty"`
+		}{IsTruncated: page+1 < len(keys)}
+
+		if page < len(keys) {
+			result.Contents = []object{{Key: keys[page]}}
+		}
+
+		if result.IsTruncated {
+			result.NextContinuationToken = strconv.Itoa(page + 1)
+		}
+
+		_ = xml.NewEncoder(w).Encode(result)
+	}))
+}
+
+func TestLogAnalyzer_S3Source(t *testing.T) {
+	server := fakeS3(t, "logs", map[string]string{
+		"2024/access.log":    strings.Repeat(tailLine, 3),
+		"2024/access.log.1":  strings.Repeat(tailLine, 5),
+		"2024/error.log":     strings.Repeat(tailLine, 7),
+		"2024/archive/a.log": strings.Repeat(tailLine, 11),
+		"2023/access.log":    strings.Repeat(tailLine, 13),
+	})
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		path     string
+		expected int
+	}{
+		{
+			name:     "Single object",
+			path:     "s3://logs/2024/access.log",
+			expected: 3,
+		},
+		{
+			name:     "Objects matching a glob",
+			path:     "s3://logs/2024/access.*",
+			expected: 8,
+		},
+		{
+			name:     "All objects under a prefix",
+			path:     "s3://logs/2024/",
+			expected: 26,
+		},
+		{
+			name:     "Whole bucket",
+			path:     "s3://logs",
+			expected: 39,
+		},
+		{
+			name:     "No matching objects",
+			path:     "s3://logs/2022/*.log",
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := application.NewLogAnalyzer([]string{tt.path}, &application.CombinedParser{})
+			analyzer.HTTP.Header = http.Header{"Authorization": {"Bearer secret"}, "X-Tenant": {"edge"}}
+			analyzer.S3 = application.S3Config{
+				Endpoint:    server.URL,
+				PathStyle:   true,
+				Credentials: application.S3Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+			}
+
+			require.NoError(t, analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", ""))
+			assert.Equal(t, tt.expected, analyzer.Metrics.TotalRequests)
+		})
+	}
+}
+
+func TestLogAnalyzer_S3SourceBucketWithDots(t *testing.T) {
+	server := fakeS3(t, "logs.example.com", map[string]string{"access.log": strings.Repeat(tailLine, 3)})
+	defer server.Close()
+
+	analyzer := application.NewLogAnalyzer([]string{"s3://logs.example.com/access.log"}, &application.CombinedParser{})
+	analyzer.S3 = application.S3Config{
+		Endpoint:    server.URL,
+		Credentials: application.S3Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+	}
+
+	require.NoError(t, analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", ""))
+	assert.Equal(t, 3, analyzer.Metrics.TotalRequests, "Buckets with dots should be addressed in the path.")
+}
@@ -0,0 +1,284 @@
+package application
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// binarySniffBytes is how much of a file is inspected to decide whether it is binary.
+const binarySniffBytes = 8 * 1024
+
+// SourceFilter selects the local files that are analyzed when directories are walked and globs are expanded.
+type SourceFilter struct {
+	// Exclude holds glob patterns of files and directories to skip. A pattern without a slash is
+	// matched against the names of files and directories, other patterns against their whole path
+	// and their path below the walked directory. A "**" element matches any number of directories.
+	Exclude    []string
+	SkipHidden bool // Skip files and directories whose names start with a dot.
+	SkipBinary bool // Skip files containing NUL bytes, except compressed files and archives.
+}
+
+// Glob returns the paths matching the pattern like filepath.Glob, except that a "**" element
+// matches any number of directories, and that excluded paths and, with SkipHidden, hidden files
+// and directories below the first wildcard are left out.
+func (f *SourceFilter) Glob(pattern string) ([]string, error) {
+	pattern = filepath.Clean(pattern)
+	if _, err := filepath.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid pattern %s: %w", pattern, err)
+	}
+
+	if !strings.ContainsAny(pattern, `*?[`) {
+		return filepath.Glob(pattern) // A path without wildcards is filtered when it is walked.
+	}
+
+	base, rest := splitGlobBase(pattern)
+
+	if !strings.Contains(rest, "**") {
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		var paths []string
+
+		for _, match := range matches {
+			if rel, err := filepath.Rel(base, match); err == nil && !f.skipPath(match, rel) {
+				paths = append(paths, match)
+			}
+		}
+
+		return paths, nil
+	}
+
+	patternSegments := strings.Split(filepath.ToSlash(rest), "/")
+
+	var paths []string
+
+	err := filepath.WalkDir(base, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == base && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+
+			return skipUnreadable(path, base, err)
+		}
+
+		if path == base {
+			return nil
+		}
+
+		rel, _ := filepath.Rel(base, path)
+		if f.skipName(entry.Name(), path, rel) {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if matchSegments(patternSegments, strings.Split(filepath.ToSlash(rel), "/")) {
+			paths = append(paths, path)
+
+			if entry.IsDir() {
+				return fs.SkipDir // Its files are found when the matched directory is walked.
+			}
+		}
+
+		return nil
+	})
+
+	return paths, err
+}
+
+// walk lists the files of a local path, walking it if it is a directory.
+func (f *SourceFilter) walk(root string) ([]string, error) {
+	var files []string
+
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return skipUnreadable(path, root, err)
+		}
+
+		rel, _ := filepath.Rel(root, path)
+
+		skip := f.isExcluded(path, rel)
+		if path != root {
+			skip = f.skipName(entry.Name(), path, rel)
+		}
+
+		if skip {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		if f.SkipBinary && isBinaryFile(path) {
+			fmt.Printf("Skipping binary file: %s\n", path)
+			return nil
+		}
+
+		files = append(files, path)
+
+		return nil
+	})
+
+	return files, err
+}
+
+// skipUnreadable reports an error of a walk. Errors below the root are printed and the entry is
+// skipped, so that one unreadable directory does not stop the walk of a tree such as /var/log.
+func skipUnreadable(path, root string, err error) error {
+	if path == root {
+		return err
+	}
+
+	fmt.Printf("Skipping %s: %v\n", path, err)
+
+	return nil
+}
+
+// skipPath reports whether a glob match is left out, checking every element of its path below the glob base.
+func (f *SourceFilter) skipPath(path, rel string) bool {
+	if f.isExcluded(path, rel) {
+		return true
+	}
+
+	if f.SkipHidden {
+		for _, name := range strings.Split(filepath.ToSlash(rel), "/") {
+			if isHidden(name) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// skipName reports whether a file or directory found by a walk is left out.
+func (f *SourceFilter) skipName(name, path, rel string) bool {
+	return f.SkipHidden && isHidden(name) || f.isExcluded(path, rel)
+}
+
+// isExcluded reports whether the path matches one of the Exclude patterns.
+func (f *SourceFilter) isExcluded(path, rel string) bool {
+	name := filepath.Base(path)
+
+	for _, pattern := range f.Exclude {
+		if !strings.Contains(pattern, "/") {
+			if matched, _ := filepath.Match(pattern, name); matched {
+				return true
+			}
+
+			continue
+		}
+
+		if MatchGlob(pattern, path) || MatchGlob(pattern, rel) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchGlob reports whether the slash-separated path matches the pattern, in which a "**" element
+// matches any number of path elements and other elements follow filepath.Match.
+func MatchGlob(pattern, path string) bool {
+	return matchSegments(strings.Split(filepath.ToSlash(pattern), "/"), strings.Split(filepath.ToSlash(path), "/"))
+}
+
+// matchSegments matches the elements of a path against the elements of a pattern.
+func matchSegments(pattern, path []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchSegments(pattern[1:], path[i:]) {
+					return true
+				}
+			}
+
+			return false
+		}
+
+		if len(path) == 0 {
+			return false
+		}
+
+		if matched, _ := filepath.Match(pattern[0], path[0]); !matched {
+			return false
+		}
+
+		pattern, path = pattern[1:], path[1:]
+	}
+
+	return len(path) == 0
+}
+
+// splitGlobBase splits a pattern into the longest leading directory without wildcards and the rest.
+func splitGlobBase(pattern string) (base, rest string) {
+	elements := strings.Split(pattern, string(filepath.Separator))
+
+	for i, element := range elements {
+		if strings.ContainsAny(element, `*?[`) {
+			base = strings.Join(elements[:i], string(filepath.Separator))
+			if base == "" {
+				base = "."
+
+				if strings.HasPrefix(pattern, string(filepath.Separator)) {
+					base = string(filepath.Separator)
+				}
+			}
+
+			return base, filepath.Join(elements[i:]...)
+		}
+	}
+
+	return ".", pattern
+}
+
+// isHidden reports whether a file name is hidden by the Unix convention.
+func isHidden(name string) bool {
+	return strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// isBinaryFile reports whether a local file looks binary: it contains a NUL byte near its start
+// and is not a compressed file or an archive, which are decompressed when analyzed.
+func isBinaryFile(path string) bool {
+	file, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer file.Close()
+
+	head := make([]byte, binarySniffBytes)
+
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return false
+	}
+
+	head = head[:n]
+
+	for _, magic := range [][]byte{gzipMagic, bzip2Magic, zstdMagic, xzMagic, zipMagic} {
+		if bytes.HasPrefix(head, magic) {
+			return false
+		}
+	}
+
+	if len(head) >= tarMagicOffset+len(tarMagic) && bytes.Equal(head[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic) {
+		return false
+	}
+
+	return bytes.IndexByte(head, 0) >= 0
+}
@@ -0,0 +1,170 @@
+package application_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeLogTree creates a /var/log-like tree with logs, rotated and temporary files, hidden files and binaries.
+func writeLogTree(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+
+	var compressed bytes.Buffer
+
+	writer := gzip.NewWriter(&compressed)
+	_, err := writer.Write([]byte(tailLine))
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+
+	files := map[string][]byte{
+		"nginx/access.log":           []byte(tailLine),
+		"nginx/access.log.1":         []byte(tailLine),
+		"nginx/error.log":            []byte("2026/10/17 10:00:00 [error] 1#1: oops\n"),
+		"nginx/access.log.2.gz.tmp":  []byte(tailLine),
+		"nginx/.access.log.swp":      {'b', '0', 'V', 'I', 'M', 0, 0, 0},
+		"nginx/archive/old.log":      []byte(tailLine),
+		"apache/site/access.log":     []byte(tailLine),
+		".cache/access.log":          []byte(tailLine),
+		"lastlog":                    {0, 0, 0, 0, 1, 2, 3},
+		"nginx/archive/access.log.3": compressed.Bytes(),
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, content, 0o600))
+	}
+
+	return dir
+}
+
+func TestSourceFilter_Glob(t *testing.T) {
+	dir := writeLogTree(t)
+
+	tests := []struct {
+		name     string
+		filter   application.SourceFilter
+		pattern  string
+		expected []string
+	}{
+		{
+			name:     "Single directory level",
+			pattern:  "nginx/access.log*",
+			expected: []string{"nginx/access.log", "nginx/access.log.1", "nginx/access.log.2.gz.tmp"},
+		},
+		{
+			name:    "Recursive glob",
+			pattern: "**/access.log",
+			expected: []string{
+				".cache/access.log", "apache/site/access.log", "nginx/access.log",
+			},
+		},
+		{
+			name:     "Recursive glob skipping hidden directories",
+			filter:   application.SourceFilter{SkipHidden: true},
+			pattern:  "**/access.log",
+			expected: []string{"apache/site/access.log", "nginx/access.log"},
+		},
+		{
+			name:     "Recursive glob in the middle",
+			filter:   application.SourceFilter{Exclude: []string{"*.tmp", "archive"}},
+			pattern:  "nginx/**/access.log*",
+			expected: []string{"nginx/access.log", "nginx/access.log.1"},
+		},
+		{
+			name:     "Exclude by path",
+			filter:   application.SourceFilter{Exclude: []string{"apache/**"}},
+			pattern:  "*/**/*.log",
+			expected: []string{".cache/access.log", "nginx/access.log", "nginx/archive/old.log", "nginx/error.log"},
+		},
+		{
+			name:     "Path without wildcards",
+			filter:   application.SourceFilter{SkipHidden: true},
+			pattern:  ".cache/access.log",
+			expected: []string{".cache/access.log"},
+		},
+		{
+			name:    "No matches",
+			pattern: "missing/**/*.log",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matches, err := tt.filter.Glob(filepath.Join(dir, filepath.FromSlash(tt.pattern)))
+			require.NoError(t, err)
+
+			var expected []string
+			for _, name := range tt.expected {
+				expected = append(expected, filepath.Join(dir, filepath.FromSlash(name)))
+			}
+
+			assert.Equal(t, expected, matches)
+		})
+	}
+}
+
+func TestLogAnalyzer_SourceFilter(t *testing.T) {
+	dir := writeLogTree(t)
+
+	analyzer := application.NewLogAnalyzer([]string{dir}, &application.CombinedParser{})
+	analyzer.SourceFilter = application.SourceFilter{
+		Exclude:    []string{"*.tmp", "error.log*"},
+		SkipHidden: true,
+		SkipBinary: true,
+	}
+
+	analyzer.Checkpoints = make(map[string]*domain.Checkpoint) // Collects the analyzed files.
+
+	require.NoError(t, analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", ""))
+
+	var names []string
+	for name := range analyzer.Checkpoints {
+		rel, err := filepath.Rel(dir, name)
+		require.NoError(t, err)
+
+		names = append(names, filepath.ToSlash(rel))
+	}
+
+	assert.ElementsMatch(t, []string{
+		"apache/site/access.log",
+		"nginx/access.log",
+		"nginx/access.log.1",
+		"nginx/archive/access.log.3",
+		"nginx/archive/old.log",
+	}, names)
+	assert.Equal(t, 5, analyzer.Metrics.TotalRequests)
+}
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern  string
+		path     string
+		expected bool
+	}{
+		{pattern: "**/*.log", path: "access.log", expected: true},
+		{pattern: "**/*.log", path: "a/b/c/access.log", expected: true},
+		{pattern: "a/**/c/*.log", path: "a/c/x.log", expected: true},
+		{pattern: "a/**/c/*.log", path: "a/b/b/c/x.log", expected: true},
+		{pattern: "a/**/c/*.log", path: "a/b/x.log", expected: false},
+		{pattern: "a/**", path: "a", expected: true},
+		{pattern: "a/*.log", path: "a/b/x.log", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.path, func(t *testing.T) {
+			assert.Equal(t, tt.expected, application.MatchGlob(tt.pattern, tt.path))
+		})
+	}
+}
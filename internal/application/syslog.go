@@ -0,0 +1,204 @@
+package application
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+const (
+	// maxSyslogMessage bounds the size of a syslog message received over TCP.
+	maxSyslogMessage = 1 << 20
+
+	// maxFrameLengthDigits bounds the length prefix of an octet-counted TCP frame.
+	maxFrameLengthDigits = 10
+
+	// rfc3164TimeLayout is the timestamp of a BSD syslog header, as NGINX writes it.
+	rfc3164TimeLayout = "Jan _2 15:04:05"
+)
+
+// StripSyslogEnvelope returns the message of an RFC 5424 or RFC 3164 syslog line, which is the log
+// line itself for NGINX access_log syslog: output. Lines without a syslog header are returned as they are.
+func StripSyslogEnvelope(line string) string {
+	rest, ok := cutSyslogPriority(line)
+	if !ok {
+		return line
+	}
+
+	if strings.HasPrefix(rest, "1 ") {
+		return stripRFC5424(rest[2:])
+	}
+
+	return stripRFC3164(rest)
+}
+
+// cutSyslogPriority removes the <PRI> part that starts every syslog header.
+func cutSyslogPriority(line string) (string, bool) {
+	if !strings.HasPrefix(line, "<") {
+		return line, false
+	}
+
+	end := strings.IndexByte(line, '>')
+	if end < 2 || end > 4 || !isDigits(line[1:end]) {
+		return line, false
+	}
+
+	return line[end+1:], true
+}
+
+// stripRFC5424 skips the TIMESTAMP HOSTNAME APP-NAME PROCID MSGID fields and the structured data
+// of an RFC 5424 header.
+func stripRFC5424(rest string) string {
+	for range 5 {
+		var ok bool
+		if _, rest, ok = strings.Cut(rest, " "); !ok {
+			return ""
+		}
+	}
+
+	if strings.HasPrefix(rest, "-") {
+		rest = rest[1:]
+	} else {
+		rest = skipStructuredData(rest)
+	}
+
+	rest = strings.TrimPrefix(rest, " ")
+
+	return strings.TrimPrefix(rest, "\ufeff") // Byte order mark of a UTF-8 message.
+}
+
+// skipStructuredData skips the [id param="value" ...] elements of RFC 5424 structured data,
+// in whose values quotes and closing brackets are escaped with backslashes.
+func skipStructuredData(rest string) string {
+	for strings.HasPrefix(rest, "[") {
+		inValue, end := false, -1
+
+		for i := 1; i < len(rest) && end < 0; i++ {
+			switch {
+			case inValue && rest[i] == '\\':
+				i++
+			case rest[i] == '"':
+				inValue = !inValue
+			case !inValue && rest[i] == ']':
+				end = i
+			}
+		}
+
+		if end < 0 {
+			return ""
+		}
+
+		rest = rest[end+1:]
+	}
+
+	return rest
+}
+
+// stripRFC3164 skips the timestamp, the optional hostname and the tag of an RFC 3164 header, such as
+// "Oct 11 22:14:15 edge-1 nginx: ". NGINX omits the hostname with the nohostname parameter.
+// Relays such as rsyslog may replace the timestamp with an RFC 3339 one.
+func stripRFC3164(rest string) string {
+	switch {
+	case len(rest) > len(rfc3164TimeLayout) && rest[len(rfc3164TimeLayout)] == ' ' &&
+		isRFC3164Time(rest[:len(rfc3164TimeLayout)]):
+		rest = rest[len(rfc3164TimeLayout)+1:]
+	default:
+		timestamp, after, ok := strings.Cut(rest, " ")
+		if _, err := time.Parse(time.RFC3339Nano, timestamp); !ok || err != nil {
+			return rest
+		}
+
+		rest = after
+	}
+
+	for range 2 {
+		token, after, ok := strings.Cut(rest, " ")
+		if !ok {
+			return rest
+		}
+
+		if strings.HasSuffix(token, ":") {
+			return after
+		}
+
+		rest = after
+	}
+
+	return rest
+}
+
+// isRFC3164Time reports whether the text is an RFC 3164 timestamp.
+func isRFC3164Time(text string) bool {
+	_, err := time.Parse(rfc3164TimeLayout, text)
+	return err == nil
+}
+
+// readSyslogFrame reads one message from a syslog TCP stream. Frames starting with a digit use
+// octet counting ("LEN MSG", RFC 6587); any other frame is terminated by a newline.
+func readSyslogFrame(reader *bufio.Reader) (string, error) {
+	first, err := reader.Peek(1)
+	if err != nil {
+		return "", err
+	}
+
+	if first[0] < '1' || first[0] > '9' {
+		return readSyslogLine(reader)
+	}
+
+	var length int
+
+	for digits := 0; ; digits++ {
+		c, err := reader.ReadByte()
+		if err != nil {
+			return "", err
+		}
+
+		if c == ' ' {
+			break
+		}
+
+		if c < '0' || c > '9' || digits == maxFrameLengthDigits {
+			return "", errors.New("invalid syslog frame length")
+		}
+
+		length = length*10 + int(c-'0')
+	}
+
+	if length > maxSyslogMessage {
+		return "", fmt.Errorf("syslog frame of %d bytes is too long", length)
+	}
+
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(reader, frame); err != nil {
+		return "", err
+	}
+
+	return strings.TrimRight(string(frame), "\r\n"), nil
+}
+
+// readSyslogLine reads a message terminated by a newline, failing once it grows longer than
+// maxSyslogMessage so that a peer never sending a newline cannot exhaust the memory.
+func readSyslogLine(reader *bufio.Reader) (string, error) {
+	var line []byte
+
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if len(line)+len(chunk) > maxSyslogMessage {
+			return "", fmt.Errorf("syslog message longer than %d bytes", maxSyslogMessage)
+		}
+
+		line = append(line, chunk...)
+
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case err != nil && (!errors.Is(err, io.EOF) || len(line) == 0):
+			return "", err
+		}
+
+		return strings.TrimRight(string(line), "\r\n"), nil
+	}
+}
@@ -0,0 +1,35 @@
+package application_test
+
+import (
+	"testing"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStripSyslogEnvelope(t *testing.T) {
+	const line = `10.0.0.1 - - [17/Oct/2026:10:00:00 +0000] "GET /a HTTP/1.1" 200 5 "-" "curl/8.0"`
+
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{name: "NGINX RFC 3164", message: "<190>Oct 17 10:00:00 edge-1 nginx: " + line},
+		{name: "NGINX without hostname", message: "<190>Oct  7 10:00:00 nginx: " + line},
+		{name: "Tag with process ID", message: "<13>Oct 17 10:00:00 edge-1 nginx[1234]: " + line},
+		{name: "RFC 3339 timestamp", message: "<190>2026-10-17T10:00:00.123+00:00 edge-1 nginx: " + line},
+		{name: "RFC 5424 without structured data", message: "<190>1 2026-10-17T10:00:00Z edge-1 nginx 1234 - - " + line},
+		{
+			name:    "RFC 5424 with structured data",
+			message: `<190>1 2026-10-17T10:00:00Z edge-1 nginx - access [meta a="x\]y" b="\"q\""][origin ip="10.0.0.2"] ` + line,
+		},
+		{name: "RFC 5424 with byte order mark", message: "<190>1 - - - - - - \ufeff" + line},
+		{name: "No envelope", message: line},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, line, application.StripSyslogEnvelope(tt.message))
+		})
+	}
+}
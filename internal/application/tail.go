@@ -0,0 +1,279 @@
+package application
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+// DefaultPollInterval is how often a followed file is checked for new lines and rotation.
+const DefaultPollInterval = 250 * time.Millisecond
+
+// truncateCheckBytes is how many of the bytes read last are compared with the file to tell whether
+// it was truncated in place and written past the read position again between two polls.
+const truncateCheckBytes = 1 << 10
+
+// FollowOptions configures LogAnalyzer.Follow.
+type FollowOptions struct {
+	PollInterval   time.Duration         // How often the file is checked, DefaultPollInterval if zero.
+	ReportInterval time.Duration         // How often Report is called, never if zero.
+	FromEnd        bool                  // Skip the lines already in the file when following starts.
+	Signals        <-chan os.Signal      // Every signal received here triggers a Report call.
+	Report         func(*domain.Metrics) // Renders the metrics collected so far.
+}
+
+// Follow reads the file at path like `tail -F` until the context is canceled: it keeps adding
+// appended lines to the analyzer metrics, reopens the path when logrotate renames the file and
+// creates a new one, and starts over when the file is truncated in place (copytruncate). Without a
+// parser, the format is detected from the lines of the file, or from the first appended lines if
+// it has none yet.
+//
+// Reading, aggregation and reporting all happen on the calling goroutine, so Report may read
+// the metrics without synchronization.
+func (a *LogAnalyzer) Follow(ctx context.Context, path string, from, to time.Time,
+	filterField, filterValue string, opts FollowOptions) error {
+	parser, err := a.followParser(path)
+	if err != nil {
+		return err
+	}
+
+	tail, err := openTailFile(path, opts.FromEnd)
+	if err != nil {
+		return err
+	}
+	defer tail.close()
+
+	sink := &lineSink{analyzer: a, source: path, parser: parser, from: from, to: to, filterField: filterField, filterValue: filterValue}
+
+	poll := time.NewTicker(cmp.Or(opts.PollInterval, DefaultPollInterval))
+	defer poll.Stop()
+
+	var reports <-chan time.Time
+
+	if opts.ReportInterval > 0 {
+		reportTicker := time.NewTicker(opts.ReportInterval)
+		defer reportTicker.Stop()
+
+		reports = reportTicker.C
+	}
+
+	report := func() {
+		sink.flush()
+		a.calculateRPS()
+
+		if opts.Report != nil {
+			opts.Report(a.Metrics)
+		}
+	}
+
+	for {
+		if err := tail.poll(sink.add); err != nil {
+			return fmt.Errorf("error following file %s: %w", path, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			sink.flush()
+			a.calculateRPS()
+
+			return nil
+		case <-poll.C:
+		case <-reports:
+			report()
+		case <-opts.Signals:
+			report()
+		}
+	}
+}
+
+// followParser picks the parser for a followed file, detecting the format from the lines it already has.
+// It returns no parser for a file without lines, whose format is detected once lines are appended.
+func (a *LogAnalyzer) followParser(path string) (domain.LogParser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	return a.parserFor(path, bufio.NewReaderSize(file, detectSampleBytes))
+}
+
+// tailFile reads the lines appended to a file and follows the path across log rotation.
+type tailFile struct {
+	path    string
+	file    *os.File
+	info    os.FileInfo
+	reader  *bufio.Reader
+	offset  int64  // Bytes of the open file read so far, including the pending partial line.
+	pending []byte // Last line of the file if it is not terminated yet.
+	last    []byte // Up to truncateCheckBytes of the bytes before offset.
+}
+
+// openTailFile opens the file at path for following, positioned at its end if fromEnd is set.
+func openTailFile(path string, fromEnd bool) (*tailFile, error) {
+	t := &tailFile{path: path}
+
+	if err := t.open(); err != nil {
+		return nil, err
+	}
+
+	if fromEnd {
+		offset, err := t.file.Seek(0, io.SeekEnd)
+		if err != nil {
+			t.close()
+			return nil, fmt.Errorf("failed to seek file %s: %w", path, err)
+		}
+
+		t.offset = offset
+		t.last = make([]byte, min(offset, truncateCheckBytes))
+
+		if _, err := t.file.ReadAt(t.last, offset-int64(len(t.last))); err != nil {
+			t.close()
+			return nil, fmt.Errorf("failed to read file %s: %w", path, err)
+		}
+	}
+
+	return t, nil
+}
+
+// open opens the file currently at the path, replacing the file being read.
+func (t *tailFile) open() error {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return fmt.Errorf("failed to open file %s: %w", t.path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to stat file %s: %w", t.path, err)
+	}
+
+	t.close()
+	t.file, t.info, t.offset, t.pending, t.last = file, info, 0, nil, nil
+	t.reader = bufio.NewReader(file)
+
+	return nil
+}
+
+// close closes the file being read.
+func (t *tailFile) close() {
+	if t.file != nil {
+		t.file.Close()
+	}
+}
+
+// poll passes the lines appended since the last poll to processLine and handles rotation.
+func (t *tailFile) poll(processLine func(string)) error {
+	if err := t.checkTruncated(); err != nil {
+		return err
+	}
+
+	if err := t.readLines(processLine); err != nil {
+		return err
+	}
+
+	info, err := os.Stat(t.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil // Renamed, but the new file is not created yet.
+	}
+
+	if err != nil || os.SameFile(t.info, info) {
+		return err
+	}
+
+	// Lines may have been written to the old file between the last read and the rename.
+	if err := t.readLines(processLine); err != nil {
+		return err
+	}
+
+	if len(t.pending) > 0 {
+		processLine(string(t.pending))
+	}
+
+	fmt.Printf("File %s was rotated, reopening\n", t.path)
+
+	if err := t.open(); err != nil {
+		return err
+	}
+
+	return t.readLines(processLine)
+}
+
+// checkTruncated starts reading the file over if it was truncated in place (copytruncate): if it is
+// shorter than the part already read, or if it no longer holds the bytes read last because it was
+// written past the read position again since the last poll.
+func (t *tailFile) checkTruncated() error {
+	info, err := t.file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() >= t.offset {
+		current := make([]byte, len(t.last))
+		if _, err := t.file.ReadAt(current, t.offset-int64(len(t.last))); err != nil {
+			return err
+		}
+
+		if bytes.Equal(current, t.last) {
+			return nil
+		}
+	}
+
+	fmt.Printf("File %s was truncated, reading from the start\n", t.path)
+
+	if _, err := t.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	t.reader.Reset(t.file)
+	t.offset, t.pending, t.last = 0, nil, nil
+
+	return nil
+}
+
+// readLines passes every complete line available in the file to processLine.
+// An unterminated last line is kept until the rest of it is written.
+func (t *tailFile) readLines(processLine func(string)) error {
+	for {
+		chunk, err := t.reader.ReadSlice('\n')
+		t.offset += int64(len(chunk))
+
+		t.last = append(t.last, chunk...)
+		if excess := len(t.last) - truncateCheckBytes; excess > 0 {
+			t.last = t.last[excess:]
+		}
+
+		switch {
+		case err == nil:
+			line := chunk[:len(chunk)-1]
+			if len(t.pending) > 0 {
+				line = append(t.pending, line...)
+			}
+
+			if len(line) > 0 && line[len(line)-1] == '\r' {
+				line = line[:len(line)-1]
+			}
+
+			processLine(string(line))
+
+			t.pending = t.pending[:0]
+		case errors.Is(err, bufio.ErrBufferFull):
+			t.pending = append(t.pending, chunk...)
+		case errors.Is(err, io.EOF):
+			t.pending = append(t.pending, chunk...)
+			return nil
+		default:
+			return err
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package application_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const tailLine = `127.0.0.1 - - [12/Dec/2021:19:01:02 +0000] "GET /index.html HTTP/1.1" 200 10 "-" "-"` + "\n"
+
+func TestLogAnalyzer_Follow(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.log")
+	require.NoError(t, os.WriteFile(path, []byte(tailLine+tailLine), 0o600))
+
+	appendLines := func(lines string) {
+		file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY|os.O_CREATE, 0o600)
+		require.NoError(t, err)
+
+		_, err = file.WriteString(lines)
+		require.NoError(t, err)
+		require.NoError(t, file.Close())
+	}
+
+	signals := make(chan os.Signal)
+	reports := make(chan int)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	analyzer := application.NewLogAnalyzer([]string{path}, nil)
+
+	go func() {
+		done <- analyzer.Follow(ctx, path, time.Time{}, time.Time{}, "", "", application.FollowOptions{
+			PollInterval: 5 * time.Millisecond,
+			Signals:      signals,
+			Report:       func(metrics *domain.Metrics) { reports <- metrics.TotalRequests },
+		})
+	}()
+
+	// Any signal asks for a report; the reported total tells what has been read so far.
+	expectTotal := func(expected int, msg string) {
+		assert.Eventually(t, func() bool {
+			signals <- os.Interrupt
+			return <-reports == expected
+		}, 5*time.Second, 10*time.Millisecond, msg)
+	}
+
+	expectTotal(2, "Existing lines should be read.")
+
+	appendLines(tailLine[:20])
+	appendLines(tailLine[20:])
+	expectTotal(3, "An appended line written in two parts should be read once.")
+
+	require.NoError(t, os.Rename(path, path+".1"))
+	appendLines(tailLine + tailLine)
+	expectTotal(5, "Lines of the file created after rotation should be read.")
+
+	require.NoError(t, os.Truncate(path, 0))
+	appendLines(tailLine)
+	expectTotal(6, "Lines written after truncation should be read.")
+
+	// Truncated and written past the read position before the next poll.
+	overwrite, err := os.OpenFile(path, os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+
+	otherLine := strings.Replace(tailLine, "127.0.0.1", "127.0.0.2", 1)
+	_, err = overwrite.WriteAt([]byte(otherLine+otherLine+otherLine), 0)
+	require.NoError(t, err)
+	require.NoError(t, overwrite.Close())
+	expectTotal(9, "A file rewritten past the read position should be read from the start.")
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, "combined", analyzer.Metrics.SourceFormats[path], "Format should be detected from the existing lines.")
+}
+
+func TestLogAnalyzer_FollowEmptyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "access.json")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	signals := make(chan os.Signal)
+	reports := make(chan int)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+
+	analyzer := application.NewLogAnalyzer([]string{path}, nil)
+
+	go func() {
+		done <- analyzer.Follow(ctx, path, time.Time{}, time.Time{}, "", "", application.FollowOptions{
+			PollInterval: 5 * time.Millisecond,
+			Signals:      signals,
+			Report:       func(metrics *domain.Metrics) { reports <- metrics.TotalRequests },
+		})
+	}()
+
+	signals <- os.Interrupt
+	assert.Equal(t, 0, <-reports, "An empty file should have no records.")
+
+	jsonLine := `{"time_iso8601":"2021-12-12T19:01:02+00:00","remote_addr":"127.0.0.1",` +
+		`"request":"GET /index.html HTTP/1.1","status":"200","body_bytes_sent":"1024"}` + "\n"
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+
+	_, err = file.WriteString(jsonLine + jsonLine)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	assert.Eventually(t, func() bool {
+		signals <- os.Interrupt
+		return <-reports == 2
+	}, 5*time.Second, 10*time.Millisecond, "Appended JSON lines should be parsed once they arrive.")
+
+	cancel()
+	require.NoError(t, <-done)
+	assert.Equal(t, "json", analyzer.Metrics.SourceFormats[path], "Format should be detected from the appended lines.")
+}
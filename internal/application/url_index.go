@@ -0,0 +1,154 @@
+package application
+
+import (
+	"encoding/json"
+	"fmt"
+	"html"
+	"io"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// maxIndexBytes bounds the size of a directory listing page.
+const maxIndexBytes = 16 << 20
+
+// indexLinkPattern matches the links of an HTML directory listing, such as an NGINX or Apache autoindex page.
+var indexLinkPattern = regexp.MustCompile(`(?i)<a\s[^>]*?href\s*=\s*["']([^"']+)["']`)
+
+// indexEntry is an entry of an NGINX autoindex page in JSON format.
+type indexEntry struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// expandURL turns a URL source naming a directory listing into the URLs of the listed files that
+// match its glob. A URL ending with a slash lists all files, one whose last segment is a glob
+// pattern lists the matching files of its directory. Other URLs are returned as they are.
+func (a *LogAnalyzer) expandURL(rawURL string) ([]string, error) {
+	indexURL, pattern, ok := splitURLPattern(rawURL)
+	if !ok {
+		return []string{rawURL}, nil
+	}
+
+	fmt.Printf("Listing URL: %s\n", indexURL)
+
+	names, err := a.listURLIndex(indexURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s: %w", indexURL, err)
+	}
+
+	var urls []string
+
+	for _, name := range names {
+		if matched, _ := path.Match(pattern, name); matched {
+			urls = append(urls, indexURL.ResolveReference(&url.URL{Path: "./" + name}).String())
+		}
+	}
+
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("no files in %s match %q", indexURL, pattern)
+	}
+
+	return urls, nil
+}
+
+// splitURLPattern splits a URL source into the URL of a directory listing and the glob applied to
+// the listed names. It reports false for URLs of single files.
+func splitURLPattern(rawURL string) (indexURL *url.URL, pattern string, ok bool) {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, "", false
+	}
+
+	dir, last := path.Split(parsed.Path)
+
+	switch {
+	case last == "":
+		pattern = "*"
+	case strings.ContainsAny(last, "*?["):
+		if _, err := path.Match(last, ""); err != nil {
+			return nil, "", false
+		}
+
+		pattern = last
+	default:
+		return nil, "", false
+	}
+
+	// The query may sign the listing request or carry an access token, so it is kept.
+	indexURL = &url.URL{Scheme: parsed.Scheme, User: parsed.User, Host: parsed.Host, Path: dir, RawQuery: parsed.RawQuery}
+
+	return indexURL, pattern, true
+}
+
+// listURLIndex downloads a directory listing and returns the sorted names of the files it lists.
+func (a *LogAnalyzer) listURLIndex(indexURL *url.URL) ([]string, error) {
+	body, err := a.HTTP.openURL(indexURL.String())
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	page, err := io.ReadAll(io.LimitReader(body, maxIndexBytes))
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	if trimmed := strings.TrimSpace(string(page)); strings.HasPrefix(trimmed, "[") {
+		names, err = jsonIndexNames(page)
+	} else {
+		names = htmlIndexNames(indexURL, string(page))
+	}
+
+	slices.Sort(names)
+
+	return slices.Compact(names), err
+}
+
+// jsonIndexNames returns the files of an NGINX autoindex page in JSON format.
+func jsonIndexNames(page []byte) ([]string, error) {
+	var entries []indexEntry
+	if err := json.Unmarshal(page, &entries); err != nil {
+		return nil, fmt.Errorf("invalid JSON directory listing: %w", err)
+	}
+
+	names := make([]string, 0, len(entries))
+
+	for _, entry := range entries {
+		if entry.Type == "file" || (entry.Type == "" && !strings.HasSuffix(entry.Name, "/")) {
+			names = append(names, entry.Name)
+		}
+	}
+
+	return names, nil
+}
+
+// htmlIndexNames returns the files linked from an HTML directory listing. Only links to files
+// directly inside the listed directory count, so parent, subdirectory and sorting links are ignored.
+func htmlIndexNames(indexURL *url.URL, page string) []string {
+	var names []string
+
+	for _, match := range indexLinkPattern.FindAllStringSubmatch(page, -1) {
+		link, err := url.Parse(html.UnescapeString(match[1]))
+		if err != nil {
+			continue
+		}
+
+		target := indexURL.ResolveReference(link)
+		if target.Host != indexURL.Host || target.RawQuery != "" {
+			continue
+		}
+
+		dir, name := path.Split(target.Path)
+		if dir == indexURL.Path && name != "" {
+			names = append(names, name)
+		}
+	}
+
+	return names
+}
@@ -0,0 +1,140 @@
+package application_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	htmlIndex = `<html><head><title>Index of /logs/</title></head><body><h1>Index of /logs/</h1><hr><pre>
+<a href="../">../</a>
+<a href="archive/">archive/</a>                                           21-Nov-2024 18:27       -
+<a href="access.log">access.log</a>                                       21-Nov-2024 18:27     170
+<a href="access.log.1">access.log.1</a>                                   21-Nov-2024 18:27      85
+<a href="error.log">error.log</a>                                         21-Nov-2024 18:27      12
+<a href="?C=M&amp;O=A">Last modified</a>
+<a href="/elsewhere/access.log">elsewhere</a>
+</pre><hr></body></html>`
+	jsonIndex = `[{"name":"archive","type":"directory","mtime":"Thu, 21 Nov 2024 18:27:47 GMT"},
+{"name":"access.log","type":"file","mtime":"Thu, 21 Nov 2024 18:27:47 GMT","size":170},
+{"name":"access.log.1","type":"file","mtime":"Thu, 21 Nov 2024 18:27:47 GMT","size":85},
+{"name":"error.log","type":"file","mtime":"Thu, 21 Nov 2024 18:27:47 GMT","size":12}]`
+)
+
+func TestLogAnalyzer_URLIndex(t *testing.T) {
+	files := map[string]string{
+		"access.log":   strings.Repeat(tailLine, 2),
+		"access.log.1": tailLine,
+		"error.log":    "not an access log\n",
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/html/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/html/" {
+			fmt.Fprint(w, htmlIndex)
+			return
+		}
+
+		fmt.Fprint(w, files[strings.TrimPrefix(r.URL.Path, "/html/")])
+	})
+	mux.HandleFunc("/json/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/json/" {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, jsonIndex)
+
+			return
+		}
+
+		fmt.Fprint(w, files[strings.TrimPrefix(r.URL.Path, "/json/")])
+	})
+
+	mux.HandleFunc("/signed/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/signed/" {
+			if r.URL.Query().Get("token") != "secret" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			fmt.Fprint(w, htmlIndex)
+
+			return
+		}
+
+		fmt.Fprint(w, files[strings.TrimPrefix(r.URL.Path, "/signed/")])
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	tests := []struct {
+		name          string
+		path          string
+		expectedFiles []string
+		expectedTotal int
+	}{
+		{
+			name:          "HTML listing with glob",
+			path:          "/html/access.log*",
+			expectedFiles: []string{"/html/access.log", "/html/access.log.1"},
+			expectedTotal: 3,
+		},
+		{
+			name:          "JSON listing with glob",
+			path:          "/json/access.log*",
+			expectedFiles: []string{"/json/access.log", "/json/access.log.1"},
+			expectedTotal: 3,
+		},
+		{
+			name:          "Listing of all files",
+			path:          "/json/",
+			expectedFiles: []string{"/json/access.log", "/json/access.log.1", "/json/error.log"},
+			expectedTotal: 3,
+		},
+		{
+			name:          "Listing URL with a token",
+			path:          "/signed/access.log*?token=secret",
+			expectedFiles: []string{"/signed/access.log", "/signed/access.log.1"},
+			expectedTotal: 3,
+		},
+		{
+			name:          "Single file",
+			path:          "/html/access.log.1",
+			expectedFiles: []string{"/html/access.log.1"},
+			expectedTotal: 1,
+		},
+		{
+			name:          "No matching files",
+			path:          "/html/*.gz",
+			expectedFiles: nil,
+			expectedTotal: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			analyzer := application.NewLogAnalyzer([]string{server.URL + tt.path}, &application.CombinedParser{})
+
+			require.NoError(t, analyzer.AnalyzeLogs(time.Time{}, time.Time{}, "", ""))
+
+			var expectedFiles []string
+			for _, file := range tt.expectedFiles {
+				expectedFiles = append(expectedFiles, server.URL+file)
+			}
+
+			if expectedFiles == nil {
+				expectedFiles = []string{server.URL + tt.path}
+			}
+
+			assert.Equal(t, expectedFiles, analyzer.Metrics.FileNames)
+			assert.Equal(t, tt.expectedTotal, analyzer.Metrics.TotalRequests)
+		})
+	}
+}
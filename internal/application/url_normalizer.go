@@ -0,0 +1,194 @@
+package application
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NormalizeOptions lists the values accepted by ParseURLNormalizer, "all" enabling every one of them.
+var NormalizeOptions = []string{"query", "slash", "lowercase", "ids"}
+
+// IDPlaceholder replaces the path segments that hold identifiers when URLNormalizer.CollapseIDs is set.
+const IDPlaceholder = "{id}"
+
+// minHashLength is the length from which a hexadecimal path segment is taken for a hash, such as an MD5 or an ObjectId.
+const minHashLength = 16
+
+// URLNormalizer turns request URLs into the resources the metrics are grouped by, so that requests
+// of the same logical endpoint, such as /users/123 and /users/456?x=1, are counted together.
+// The zero value keeps URLs as they are.
+type URLNormalizer struct {
+	StripQuery  bool    // Drop the query string.
+	TrimSlash   bool    // Drop the trailing slash of paths other than "/".
+	Lowercase   bool    // Lower-case the URL.
+	CollapseIDs bool    // Replace numeric IDs, UUIDs and hexadecimal hashes with IDPlaceholder.
+	Routes      []Route // Route patterns tried in order; the first match replaces the URL.
+}
+
+// Route is a pattern such as /users/:id/orders. A segment starting with a colon matches any single
+// path segment and a final "*" matches the rest of the path. Requests matching a route are counted
+// under the pattern itself, without their query string.
+type Route struct {
+	Pattern  string
+	segments []string
+}
+
+// ParseRoute parses a route pattern.
+func ParseRoute(pattern string) (Route, error) {
+	if !strings.HasPrefix(pattern, "/") {
+		return Route{}, fmt.Errorf("invalid route %q: it must start with a slash", pattern)
+	}
+
+	segments := splitPath(pattern)
+
+	for i, segment := range segments {
+		if segment == "*" && i != len(segments)-1 {
+			return Route{}, fmt.Errorf("invalid route %q: * must be the last segment", pattern)
+		}
+
+		if segment == ":" {
+			return Route{}, fmt.Errorf("invalid route %q: parameter without a name", pattern)
+		}
+	}
+
+	return Route{Pattern: pattern, segments: segments}, nil
+}
+
+// ParseURLNormalizer creates a normalizer from normalization options, such as "query" or "all", and route patterns.
+func ParseURLNormalizer(options, routes []string) (URLNormalizer, error) {
+	var normalizer URLNormalizer
+
+	for _, option := range options {
+		switch strings.TrimSpace(option) {
+		case "query":
+			normalizer.StripQuery = true
+		case "slash":
+			normalizer.TrimSlash = true
+		case "lowercase":
+			normalizer.Lowercase = true
+		case "ids":
+			normalizer.CollapseIDs = true
+		case "all":
+			normalizer.StripQuery, normalizer.TrimSlash, normalizer.Lowercase, normalizer.CollapseIDs = true, true, true, true
+		default:
+			return URLNormalizer{}, fmt.Errorf("unknown normalization %q (available: %s, all)", option, strings.Join(NormalizeOptions, ", "))
+		}
+	}
+
+	for _, pattern := range routes {
+		route, err := ParseRoute(pattern)
+		if err != nil {
+			return URLNormalizer{}, err
+		}
+
+		normalizer.Routes = append(normalizer.Routes, route)
+	}
+
+	return normalizer, nil
+}
+
+// Normalize returns the resource a request URL is counted under.
+func (n *URLNormalizer) Normalize(rawURL string) string {
+	if !n.StripQuery && !n.TrimSlash && !n.Lowercase && !n.CollapseIDs && len(n.Routes) == 0 {
+		return rawURL
+	}
+
+	path, query, hasQuery := strings.Cut(rawURL, "?")
+
+	if n.Lowercase {
+		path, query = strings.ToLower(path), strings.ToLower(query)
+	}
+
+	for _, route := range n.Routes {
+		if route.matches(path) {
+			return route.Pattern
+		}
+	}
+
+	if n.TrimSlash && len(path) > 1 {
+		path = strings.TrimRight(path, "/")
+		if path == "" {
+			path = "/"
+		}
+	}
+
+	if n.CollapseIDs {
+		path = collapseIDs(path)
+	}
+
+	if hasQuery && !n.StripQuery {
+		return path + "?" + query
+	}
+
+	return path
+}
+
+// matches reports whether a URL path matches the route. A trailing slash of the path is ignored.
+func (r Route) matches(path string) bool {
+	segments := splitPath(path)
+
+	for i, pattern := range r.segments {
+		if pattern == "*" {
+			return true
+		}
+
+		if i >= len(segments) || !strings.HasPrefix(pattern, ":") && pattern != segments[i] {
+			return false
+		}
+	}
+
+	return len(segments) == len(r.segments)
+}
+
+// splitPath splits a URL path into its segments, ignoring the leading and trailing slashes.
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return nil
+	}
+
+	return strings.Split(path, "/")
+}
+
+// collapseIDs replaces the segments of a URL path that hold identifiers with IDPlaceholder.
+func collapseIDs(path string) string {
+	segments := strings.Split(path, "/")
+
+	for i, segment := range segments {
+		if isID(segment) {
+			segments[i] = IDPlaceholder
+		}
+	}
+
+	return strings.Join(segments, "/")
+}
+
+// isID reports whether a path segment is a numeric ID, a UUID or a hexadecimal hash.
+func isID(segment string) bool {
+	if segment == "" {
+		return false
+	}
+
+	digits, hex := 0, 0
+
+	for i := 0; i < len(segment); i++ {
+		switch c := segment[i]; {
+		case c >= '0' && c <= '9':
+			digits++
+		case c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F':
+			hex++
+		case c == '-' && len(segment) == 36 && (i == 8 || i == 13 || i == 18 || i == 23):
+		default:
+			return false
+		}
+	}
+
+	switch {
+	case hex == 0:
+		return true // A number, or digits separated like a UUID.
+	case len(segment) == 36:
+		return strings.Count(segment, "-") == 4 // A UUID.
+	default:
+		return digits > 0 && len(segment) >= minHashLength && !strings.Contains(segment, "-")
+	}
+}
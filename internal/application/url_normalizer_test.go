@@ -0,0 +1,77 @@
+package application_test
+
+import (
+	"testing"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLNormalizer_Normalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		options  []string
+		routes   []string
+		url      string
+		expected string
+	}{
+		{name: "No normalization", url: "/Users/123/?x=1", expected: "/Users/123/?x=1"},
+		{name: "Strip query", options: []string{"query"}, url: "/users/456?x=1", expected: "/users/456"},
+		{name: "Trim slash", options: []string{"slash"}, url: "/users/789/?x=1", expected: "/users/789?x=1"},
+		{name: "Keep root slash", options: []string{"slash"}, url: "/", expected: "/"},
+		{name: "Lowercase", options: []string{"lowercase"}, url: "/Users/ABC", expected: "/users/abc"},
+		{name: "Numeric ID", options: []string{"ids"}, url: "/users/123/orders/45", expected: "/users/{id}/orders/{id}"},
+		{
+			name:     "UUID",
+			options:  []string{"ids"},
+			url:      "/files/3F2504E0-4F89-11D3-9A0C-0305E82C3301",
+			expected: "/files/{id}",
+		},
+		{name: "Hash", options: []string{"ids"}, url: "/blobs/d41d8cd98f00b204e9800998ecf8427e", expected: "/blobs/{id}"},
+		{name: "Words are not IDs", options: []string{"ids"}, url: "/v2/decaf/api-v1/2024-10-17", expected: "/v2/decaf/api-v1/2024-10-17"},
+		{name: "All", options: []string{"all"}, url: "/Users/123/?x=1", expected: "/users/{id}"},
+		{
+			name:     "Route with a parameter",
+			routes:   []string{"/users/:id/orders"},
+			url:      "/users/alice/orders/?page=2",
+			expected: "/users/:id/orders",
+		},
+		{name: "Route with a wildcard", routes: []string{"/static/*"}, url: "/static/css/site.css", expected: "/static/*"},
+		{
+			name:     "Unmatched route",
+			options:  []string{"ids"},
+			routes:   []string{"/users/:id"},
+			url:      "/users/1/orders",
+			expected: "/users/{id}/orders",
+		},
+		{name: "First matching route", routes: []string{"/users/me", "/users/:id"}, url: "/users/me", expected: "/users/me"},
+		{
+			name:     "Lowercase before routes",
+			options:  []string{"lowercase"},
+			routes:   []string{"/users/:id"},
+			url:      "/USERS/1",
+			expected: "/users/:id",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalizer, err := application.ParseURLNormalizer(tt.options, tt.routes)
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.expected, normalizer.Normalize(tt.url))
+		})
+	}
+}
+
+func TestParseURLNormalizer_Invalid(t *testing.T) {
+	_, err := application.ParseURLNormalizer([]string{"fragment"}, nil)
+	assert.Error(t, err, "Unknown options should be rejected.")
+
+	_, err = application.ParseURLNormalizer(nil, []string{"users/:id"})
+	assert.Error(t, err, "Routes without a leading slash should be rejected.")
+
+	_, err = application.ParseURLNormalizer(nil, []string{"/static/*/css"})
+	assert.Error(t, err, "A wildcard before the last segment should be rejected.")
+}
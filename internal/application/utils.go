@@ -1,5 +1,13 @@
 package application
 
+const (
+	// StdinPath is the path that makes the analyzer read logs from the standard input.
+	StdinPath = "-"
+
+	// stdinSource is the name the standard input is reported under.
+	stdinSource = "stdin"
+)
+
 // IsURL checks if a given path is a URL.
 func IsURL(path string) bool {
 	return len(path) > 4 && (path[:4] == "http" || path[:5] == "https")
@@ -0,0 +1,38 @@
+package domain
+
+import "time"
+
+// StateVersion is the version of the State layout written by this build.
+const StateVersion = 1
+
+// Checkpoint records how far a local file was analyzed, so that a later run only has to analyze
+// the bytes appended since.
+type Checkpoint struct {
+	Device        uint64 // Device and Inode identify the file across renames; zero where unsupported.
+	Inode         uint64
+	Size          int64 // Size of the file when it was analyzed.
+	ModTime       time.Time
+	Offset        int64  // Number of bytes analyzed.
+	LastLineStart int64  // Start of the last line before Offset, or Offset minus 4 KiB for longer lines.
+	LastLineHash  string // SHA-256 of the bytes from LastLineStart to Offset.
+	Fingerprint   string // SHA-256 of the first 64 KiB analyzed, recognizing the content once it is compressed.
+	ContentSize   int64  // Number of bytes the Fingerprint stands for; zero for compressed files and archives.
+}
+
+// State is what an incremental analysis keeps between runs: the metrics collected so far and
+// a checkpoint for every file they were collected from.
+type State struct {
+	Version int
+	Filters string                 // Time range and filter the metrics were collected with.
+	Sources map[string]*Checkpoint // Checkpoint per file path.
+	Metrics *Metrics
+}
+
+// NewState creates an empty state.
+func NewState() *State {
+	return &State{
+		Version: StateVersion,
+		Sources: make(map[string]*Checkpoint),
+		Metrics: NewMetrics(nil),
+	}
+}
@@ -0,0 +1,88 @@
+package domain
+
+import "net/netip"
+
+// ClientStats aggregates the requests of a single client IP or subnet.
+type ClientStats struct {
+	Requests  int
+	Bytes     int
+	Errors    int                 // Requests answered with a 4xx or 5xx status.
+	Resources map[string]struct{} // Distinct resources requested.
+}
+
+// NewClientStats creates empty client statistics.
+func NewClientStats() *ClientStats {
+	return &ClientStats{Resources: make(map[string]struct{})}
+}
+
+// Add records a single request of the client.
+func (s *ClientStats) Add(resource string, statusCode, bytes int) {
+	s.Requests++
+	s.Bytes += bytes
+	s.Resources[resource] = struct{}{}
+
+	if IsErrorStatus(statusCode) {
+		s.Errors++
+	}
+}
+
+// Merge adds the requests recorded by other.
+func (s *ClientStats) Merge(other *ClientStats) {
+	s.Requests += other.Requests
+	s.Bytes += other.Bytes
+	s.Errors += other.Errors
+
+	for resource := range other.Resources {
+		s.Resources[resource] = struct{}{}
+	}
+}
+
+// ErrorRate returns the share of requests answered with an error status, or zero without requests.
+func (s *ClientStats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// RollupClients groups the statistics of client IPs by subnet, such as 192.168.1.0/24: IPv4 addresses
+// by their first ipv4Bits bits and IPv6 addresses by their first ipv6Bits bits. IPv6 addresses are
+// left out if ipv6Bits is zero, and clients that are not IP addresses are kept as they are.
+func RollupClients(clients map[string]*ClientStats, ipv4Bits, ipv6Bits int) map[string]*ClientStats {
+	subnets := make(map[string]*ClientStats)
+
+	for client, stats := range clients {
+		subnet := client
+
+		if addr, err := netip.ParseAddr(client); err == nil {
+			addr = addr.Unmap()
+
+			bits := ipv4Bits
+			if addr.Is6() {
+				bits = ipv6Bits
+			}
+
+			if bits == 0 {
+				continue
+			}
+
+			prefix, err := addr.WithZone("").Prefix(bits)
+			if err != nil {
+				continue
+			}
+
+			subnet = prefix.String()
+		}
+
+		merged, ok := subnets[subnet]
+		if !ok {
+			merged = NewClientStats()
+			subnets[subnet] = merged
+		}
+
+		merged.Merge(stats)
+	}
+
+	return subnets
+}
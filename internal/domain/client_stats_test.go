@@ -0,0 +1,50 @@
+package domain_test
+
+import (
+	"testing"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRollupClients(t *testing.T) {
+	clients := make(map[string]*domain.ClientStats)
+
+	requests := []struct {
+		ip       string
+		resource string
+		status   int
+		bytes    int
+	}{
+		{"192.168.1.10", "/a", 200, 100},
+		{"192.168.1.20", "/b", 500, 200},
+		{"192.168.2.30", "/a", 404, 300},
+		{"::ffff:192.168.1.40", "/c", 200, 400},
+		{"2001:db8:1:2::1", "/a", 200, 500},
+		{"2001:db8:1:3::1", "/b", 200, 600},
+		{"unknown", "/a", 200, 700},
+	}
+
+	for _, r := range requests {
+		if _, ok := clients[r.ip]; !ok {
+			clients[r.ip] = domain.NewClientStats()
+		}
+
+		clients[r.ip].Add(r.resource, r.status, r.bytes)
+	}
+
+	subnets := domain.RollupClients(clients, 24, 48)
+	assert.Len(t, subnets, 4, "Subnets mismatch.")
+
+	local := subnets["192.168.1.0/24"]
+	assert.Equal(t, 3, local.Requests, "IPv4-mapped addresses should be rolled up with IPv4 ones.")
+	assert.Equal(t, 700, local.Bytes, "Bytes mismatch.")
+	assert.InDelta(t, 1.0/3, local.ErrorRate(), 1e-9, "Error rate mismatch.")
+	assert.Len(t, local.Resources, 3, "Distinct resources mismatch.")
+	assert.Equal(t, 2, subnets["2001:db8:1::/48"].Requests, "IPv6 addresses should be rolled up by /48.")
+	assert.Equal(t, 1, subnets["unknown"].Requests, "Clients that are not IPs should be kept.")
+
+	subnets = domain.RollupClients(clients, 16, 0)
+	assert.Equal(t, 4, subnets["192.168.0.0/16"].Requests, "Requests of the /16 mismatch.")
+	assert.NotContains(t, subnets, "2001:db8::/16", "IPv6 addresses should be left out without a prefix length.")
+}
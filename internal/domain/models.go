@@ -1,9 +1,19 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"slices"
+	"time"
+)
 
+// ErrSkipLine is returned by parsers for lines that carry no request, such as comments and headers.
+var ErrSkipLine = errors.New("line holds no log record")
+
+// LogRecord is a single parsed entry of an access log.
 type LogRecord struct {
 	IP           string
+	Ident        string // RFC 1413 identity of the client, empty when logged as "-".
+	RemoteUser   string // Authenticated user name, empty when logged as "-".
 	Timestamp    time.Time
 	Method       string
 	URL          string
@@ -12,6 +22,74 @@ type LogRecord struct {
 	ResponseSize int
 	Referer      string
 	UserAgent    string
+	Extra        map[string]string  // Format-specific fields without a dedicated field above.
+	Timings      map[string]float64 // Format-specific durations in seconds, such as ALB processing times.
+
+	RequestTime     float64 // Seconds taken to serve the request, such as NGINX $request_time.
+	UpstreamTime    float64 // Seconds spent waiting for upstream servers, such as NGINX $upstream_response_time.
+	HasRequestTime  bool    // Whether the line carries RequestTime.
+	HasUpstreamTime bool    // Whether the line carries UpstreamTime.
+}
+
+// Latency returns the time taken to serve the request: the request time if it was logged,
+// otherwise the upstream response time. It reports false for lines without timing.
+func (r *LogRecord) Latency() (float64, bool) {
+	switch {
+	case r.HasRequestTime:
+		return r.RequestTime, true
+	case r.HasUpstreamTime:
+		return r.UpstreamTime, true
+	default:
+		return 0, false
+	}
+}
+
+// IsErrorStatus reports whether a status code counts as an error: a client or a server error.
+func IsErrorStatus(code int) bool {
+	return code >= 400
+}
+
+// TimingStats aggregates one kind of duration reported by the log format.
+type TimingStats struct {
+	Count int
+	Total float64 // Seconds
+	Max   float64 // Seconds
+}
+
+// LatencyStats aggregates the latency of a set of requests.
+type LatencyStats struct {
+	TimingStats
+	Durations *QuantileSketch // Distribution of latencies in seconds for percentiles.
+}
+
+// NewLatencyStats creates empty latency statistics.
+func NewLatencyStats() *LatencyStats {
+	return &LatencyStats{Durations: NewQuantileSketch()}
+}
+
+// Add records the latency of a single request.
+func (s *LatencyStats) Add(seconds float64) {
+	s.Count++
+	s.Total += seconds
+	s.Max = max(s.Max, seconds)
+	s.Durations.Add(seconds)
+}
+
+// Merge adds the latencies recorded by other.
+func (s *LatencyStats) Merge(other *LatencyStats) {
+	s.Count += other.Count
+	s.Total += other.Total
+	s.Max = max(s.Max, other.Max)
+	s.Durations.Merge(other.Durations)
+}
+
+// Average returns the mean latency in seconds, or zero without requests.
+func (s *LatencyStats) Average() float64 {
+	if s.Count == 0 {
+		return 0
+	}
+
+	return s.Total / float64(s.Count)
 }
 
 // Metrics stores statistics from analyzed logs.
@@ -22,29 +100,135 @@ type Metrics struct {
 	TotalRequests   int
 	TotalRespSize   int
 	AverageRespSize float64
-	Percentile95    int
-	ResponseSizes   []int
-	Resources       map[string]int
+	ResponseSizes   *QuantileSketch // Distribution of response sizes for percentiles.
+	Users           map[string]int  // Requests per authenticated user.
 	StatusCodes     map[int]int
 	UniqueIPs       map[string]struct{} // To track unique IPs
 	RPS             float64             // Requests Per Second
+	SourceFormats   map[string]string   // Detected log format per source
+	Timings         map[string]*TimingStats
+	Latency         *LatencyStats             // Latency of the requests whose lines carry timing.
+	UntimedRequests int                       // Requests whose lines carry no timing.
+	Timeline        *Timeline                 // Requests per time interval.
+	ResourceStats   map[string]*ResourceStats // Status codes, sizes, methods and latency per resource.
+	Clients         map[string]*ClientStats   // Requests, bytes, errors and resources per client IP.
 }
 
 // NewMetrics initializes a new Metrics instance.
 func NewMetrics(fileNames []string) *Metrics {
 	return &Metrics{
 		FileNames:     fileNames,
-		Resources:     make(map[string]int),
+		Users:         make(map[string]int),
 		StatusCodes:   make(map[int]int),
-		ResponseSizes: make([]int, 0),
+		ResponseSizes: NewQuantileSketch(),
 		UniqueIPs:     make(map[string]struct{}),
+		SourceFormats: make(map[string]string),
+		Timings:       make(map[string]*TimingStats),
+		Latency:       NewLatencyStats(),
+		ResourceStats: make(map[string]*ResourceStats),
+		Clients:       make(map[string]*ClientStats),
+		Timeline:      NewTimeline(0),
 	}
 }
 
+// Merge adds the statistics collected in other to m. Merging the metrics of disjoint sets of records
+// yields the same result as collecting them in one pass, except for RPS, which has to be recalculated.
+func (m *Metrics) Merge(other *Metrics) {
+	for _, name := range other.FileNames {
+		if !slices.Contains(m.FileNames, name) {
+			m.FileNames = append(m.FileNames, name)
+		}
+	}
+
+	if other.TotalRequests > 0 {
+		if m.StartDate.IsZero() || m.StartDate.After(other.StartDate) {
+			m.StartDate = other.StartDate
+		}
+
+		if m.EndDate.IsZero() || m.EndDate.Before(other.EndDate) {
+			m.EndDate = other.EndDate
+		}
+	}
+
+	m.TotalRequests += other.TotalRequests
+	m.TotalRespSize += other.TotalRespSize
+
+	if m.TotalRequests > 0 {
+		m.AverageRespSize = float64(m.TotalRespSize) / float64(m.TotalRequests)
+	}
+
+	m.ResponseSizes.Merge(other.ResponseSizes)
+
+	mergeCounts(m.Users, other.Users)
+	mergeCounts(m.StatusCodes, other.StatusCodes)
+
+	for ip := range other.UniqueIPs {
+		m.UniqueIPs[ip] = struct{}{}
+	}
+
+	for source, format := range other.SourceFormats {
+		m.SourceFormats[source] = format
+	}
+
+	for name, stats := range other.Timings {
+		merged, ok := m.Timings[name]
+		if !ok {
+			merged = &TimingStats{}
+			m.Timings[name] = merged
+		}
+
+		merged.Count += stats.Count
+		merged.Total += stats.Total
+		merged.Max = max(merged.Max, stats.Max)
+	}
+
+	m.Latency.Merge(other.Latency)
+	m.UntimedRequests += other.UntimedRequests
+
+	mergeStats(m.ResourceStats, other.ResourceStats, NewResourceStats)
+	mergeStats(m.Clients, other.Clients, NewClientStats)
+
+	m.Timeline.Merge(other.Timeline)
+}
+
+// mergeCounts adds the counters of src to dst.
+func mergeCounts[K comparable](dst, src map[K]int) {
+	for key, count := range src {
+		dst[key] += count
+	}
+}
+
+// mergeStats merges the statistics of src into dst, creating the entries missing from dst with newStats.
+func mergeStats[S interface{ Merge(S) }](dst, src map[string]S, newStats func() S) {
+	for key, stats := range src {
+		merged, ok := dst[key]
+		if !ok {
+			merged = newStats()
+			dst[key] = merged
+		}
+
+		merged.Merge(stats)
+	}
+}
+
+// LogParser turns a single raw log line into a LogRecord.
 type LogParser interface {
 	ParseLogLine(line string) (LogRecord, error)
 }
 
+// StatefulParser is implemented by parsers that keep state between the lines of a source,
+// such as the field list of a W3C #Fields header. Clone returns a fresh parser for a new source.
+type StatefulParser interface {
+	LogParser
+	Clone() LogParser
+}
+
+// ByteParser is implemented by parsers that can parse a line straight from the scanner buffer.
+// The buffer is reused by the next scan, so the parser must copy everything the record keeps.
+type ByteParser interface {
+	ParseLogBytes(line []byte, record *LogRecord) error
+}
+
 type StreamReader interface {
 	ReadLine() (string, error)
 }
@@ -0,0 +1,92 @@
+package domain_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics_Merge(t *testing.T) {
+	first := domain.NewMetrics([]string{"a.log"})
+	first.StartDate = time.Date(2021, time.December, 12, 0, 0, 0, 0, time.UTC)
+	first.EndDate = time.Date(2021, time.December, 13, 0, 0, 0, 0, time.UTC)
+	first.TotalRequests = 2
+	first.TotalRespSize = 300
+	first.ResponseSizes.Add(100)
+	first.ResponseSizes.Add(200)
+	first.StatusCodes[200] = 2
+	first.UniqueIPs["10.0.0.1"] = struct{}{}
+	first.Timings["time_taken"] = &domain.TimingStats{Count: 2, Total: 0.5, Max: 0.3}
+	first.Latency.Add(0.2)
+	first.ResourceStats["/index.html"] = domain.NewResourceStats()
+	first.ResourceStats["/index.html"].Add(&domain.LogRecord{
+		Method: "GET", StatusCode: 200, ResponseSize: 100, RequestTime: 0.2, HasRequestTime: true,
+	})
+	first.UntimedRequests = 1
+	first.Clients["10.0.0.1"] = domain.NewClientStats()
+	first.Clients["10.0.0.1"].Add("/index.html", 200, 100)
+
+	second := domain.NewMetrics([]string{"a.log", "b.log"})
+	second.StartDate = time.Date(2021, time.December, 11, 0, 0, 0, 0, time.UTC)
+	second.EndDate = time.Date(2021, time.December, 12, 12, 0, 0, 0, time.UTC)
+	second.TotalRequests = 1
+	second.TotalRespSize = 600
+	second.ResponseSizes.Add(600)
+	second.StatusCodes[404] = 1
+	second.Users["alice"] = 1
+	second.UniqueIPs["10.0.0.2"] = struct{}{}
+	second.SourceFormats["b.log"] = "combined"
+	second.Timings["time_taken"] = &domain.TimingStats{Count: 1, Total: 0.7, Max: 0.7}
+	second.Latency.Add(0.6)
+	second.ResourceStats["/index.html"] = domain.NewResourceStats()
+	second.ResourceStats["/index.html"].Add(&domain.LogRecord{
+		Method: "POST", StatusCode: 404, ResponseSize: 600, RequestTime: 0.6, HasRequestTime: true,
+	})
+	second.ResourceStats["/robots.txt"] = domain.NewResourceStats()
+	second.ResourceStats["/robots.txt"].Add(&domain.LogRecord{Method: "GET", StatusCode: 200})
+	second.Clients["10.0.0.1"] = domain.NewClientStats()
+	second.Clients["10.0.0.1"].Add("/robots.txt", 404, 600)
+
+	first.Merge(second)
+
+	assert.Equal(t, []string{"a.log", "b.log"}, first.FileNames, "FileNames mismatch.")
+	assert.Equal(t, second.StartDate, first.StartDate, "StartDate mismatch.")
+	assert.Equal(t, time.Date(2021, time.December, 13, 0, 0, 0, 0, time.UTC), first.EndDate, "EndDate mismatch.")
+	assert.Equal(t, 3, first.TotalRequests, "TotalRequests mismatch.")
+	assert.InDelta(t, 300.0, first.AverageRespSize, 1e-9, "AverageRespSize mismatch.")
+	assert.Equal(t, uint64(3), first.ResponseSizes.Count, "ResponseSizes count mismatch.")
+	assert.Equal(t, map[int]int{200: 2, 404: 1}, first.StatusCodes, "StatusCodes mismatch.")
+	assert.Equal(t, map[string]int{"alice": 1}, first.Users, "Users mismatch.")
+	assert.Len(t, first.UniqueIPs, 2, "UniqueIPs mismatch.")
+	assert.Equal(t, map[string]string{"b.log": "combined"}, first.SourceFormats, "SourceFormats mismatch.")
+	assert.Equal(t, &domain.TimingStats{Count: 3, Total: 1.2, Max: 0.7}, first.Timings["time_taken"], "Timings mismatch.")
+	assert.Equal(t, 1, first.UntimedRequests, "UntimedRequests mismatch.")
+	assert.Equal(t, 2, first.Latency.Count, "Latency count mismatch.")
+	assert.InDelta(t, 0.4, first.Latency.Average(), 1e-9, "Average latency mismatch.")
+	assert.InDelta(t, 0.6, first.Latency.Max, 1e-9, "Max latency mismatch.")
+
+	index := first.ResourceStats["/index.html"]
+	assert.Equal(t, 2, index.Requests, "Resource requests mismatch.")
+	assert.Equal(t, 700, index.Bytes, "Resource bytes mismatch.")
+	assert.Equal(t, map[string]int{"GET": 1, "POST": 1}, index.Methods, "Resource methods mismatch.")
+	assert.Equal(t, map[int]int{200: 1, 404: 1}, index.StatusCodes, "Resource status codes mismatch.")
+	assert.InDelta(t, 0.5, index.ErrorRate(), 1e-9, "Resource error rate mismatch.")
+	assert.Equal(t, uint64(2), index.Latency.Durations.Count, "Resource latency mismatch.")
+	assert.Nil(t, first.ResourceStats["/robots.txt"].Latency, "A resource without timing should have no latency.")
+	assert.Equal(t, &domain.ClientStats{
+		Requests:  2,
+		Bytes:     700,
+		Errors:    1,
+		Resources: map[string]struct{}{"/index.html": {}, "/robots.txt": {}},
+	}, first.Clients["10.0.0.1"], "Clients mismatch.")
+}
+
+func TestMetrics_MergeEmpty(t *testing.T) {
+	metrics := domain.NewMetrics(nil)
+	metrics.Merge(domain.NewMetrics(nil))
+
+	assert.True(t, metrics.StartDate.IsZero(), "Merging empty metrics should keep StartDate unset.")
+	assert.Zero(t, metrics.AverageRespSize, "AverageRespSize should stay zero.")
+}
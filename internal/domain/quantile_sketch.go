@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"maps"
+	"math"
+	"slices"
+)
+
+const (
+	// DefaultSketchAccuracy is the relative accuracy of quantiles returned by sketches created with NewQuantileSketch.
+	DefaultSketchAccuracy = 0.01
+	// DefaultSketchBins bounds the number of bins of a sketch. With 1% accuracy 2048 bins cover
+	// values from 1 to about 10^17 without any loss of accuracy.
+	DefaultSketchBins = 2048
+)
+
+// QuantileSketch is a DDSketch: a streaming, mergeable summary of non-negative values that answers
+// quantile queries with a relative error guarantee. For every quantile q the estimate v' of the
+// true value v satisfies |v' - v| <= RelativeAccuracy * v, as long as the sketch has not exceeded
+// MaxBins. Memory is bounded by MaxBins; once it is exceeded the lowest bins are collapsed, so only
+// the smallest values lose accuracy. Values below or equal to zero are counted in a dedicated bin.
+//
+// The fields are exported so that sketches can be serialized together with Metrics.
+type QuantileSketch struct {
+	RelativeAccuracy float64
+	MaxBins          int
+	Bins             map[int]uint64 // Value count per logarithmic bin index.
+	ZeroCount        uint64
+	Count            uint64
+	Min              float64
+	Max              float64
+
+	lnGamma float64
+}
+
+// NewQuantileSketch creates an empty sketch with the default accuracy and size bound.
+func NewQuantileSketch() *QuantileSketch {
+	return &QuantileSketch{
+		RelativeAccuracy: DefaultSketchAccuracy,
+		MaxBins:          DefaultSketchBins,
+		Bins:             make(map[int]uint64),
+	}
+}
+
+// gamma returns the logarithm of the bin growth factor (1+a)/(1-a).
+func (s *QuantileSketch) gamma() float64 {
+	if s.RelativeAccuracy <= 0 || s.RelativeAccuracy >= 1 {
+		s.RelativeAccuracy = DefaultSketchAccuracy
+	}
+
+	if s.lnGamma == 0 {
+		s.lnGamma = math.Log((1 + s.RelativeAccuracy) / (1 - s.RelativeAccuracy))
+	}
+
+	return s.lnGamma
+}
+
+// Add records a single value.
+func (s *QuantileSketch) Add(value float64) {
+	if s.Count == 0 || value < s.Min {
+		s.Min = value
+	}
+
+	if s.Count == 0 || value > s.Max {
+		s.Max = value
+	}
+
+	s.Count++
+
+	if value <= 0 {
+		s.ZeroCount++
+		return
+	}
+
+	if s.Bins == nil {
+		s.Bins = make(map[int]uint64)
+	}
+
+	s.Bins[int(math.Ceil(math.Log(value)/s.gamma()))]++
+	s.collapse()
+}
+
+// Merge adds all values recorded by the other sketch. Both sketches must have the same accuracy.
+func (s *QuantileSketch) Merge(other *QuantileSketch) {
+	if other == nil || other.Count == 0 {
+		return
+	}
+
+	if s.Count == 0 || other.Min < s.Min {
+		s.Min = other.Min
+	}
+
+	if s.Count == 0 || other.Max > s.Max {
+		s.Max = other.Max
+	}
+
+	s.Count += other.Count
+	s.ZeroCount += other.ZeroCount
+
+	if s.Bins == nil {
+		s.Bins = make(map[int]uint64, len(other.Bins))
+	}
+
+	for index, count := range other.Bins {
+		s.Bins[index] += count
+	}
+
+	s.collapse()
+}
+
+// Quantile returns the estimated value at quantile q in [0, 1], or zero for an empty sketch.
+func (s *QuantileSketch) Quantile(q float64) float64 {
+	if s.Count == 0 {
+		return 0
+	}
+
+	rank := uint64(q * float64(s.Count-1))
+	if rank < s.ZeroCount {
+		return math.Max(s.Min, 0)
+	}
+
+	seen := s.ZeroCount
+
+	for _, index := range slices.Sorted(maps.Keys(s.Bins)) {
+		seen += s.Bins[index]
+		if seen > rank {
+			// The midpoint of the bin (gamma^(i-1), gamma^i] in terms of relative error.
+			value := 2 * math.Exp(float64(index)*s.gamma()) / (1 + math.Exp(s.gamma()))
+			return math.Min(math.Max(value, s.Min), s.Max)
+		}
+	}
+
+	return s.Max
+}
+
+// collapse merges the lowest bins while the sketch holds more than MaxBins bins.
+func (s *QuantileSketch) collapse() {
+	if s.MaxBins <= 0 || len(s.Bins) <= s.MaxBins {
+		return
+	}
+
+	indexes := slices.Sorted(maps.Keys(s.Bins))
+	excess := len(indexes) - s.MaxBins
+	target := indexes[excess]
+
+	for _, index := range indexes[:excess] {
+		s.Bins[target] += s.Bins[index]
+		delete(s.Bins, index)
+	}
+}
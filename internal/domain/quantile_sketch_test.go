@@ -0,0 +1,88 @@
+package domain_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func exactQuantile(sorted []float64, q float64) float64 {
+	return sorted[int(q*float64(len(sorted)-1))]
+}
+
+func TestQuantileSketch_RelativeAccuracy(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+
+	testCases := []struct {
+		name     string
+		generate func() float64
+	}{
+		{
+			name:     "Uniform sizes",
+			generate: func() float64 { return float64(rng.Intn(100000)) },
+		},
+		{
+			name:     "Heavy tailed sizes",
+			generate: func() float64 { return math.Round(math.Exp(rng.NormFloat64()*3 + 8)) },
+		},
+		{
+			name:     "Latencies in seconds",
+			generate: func() float64 { return rng.ExpFloat64() / 10 },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			sketch := domain.NewQuantileSketch()
+			values := make([]float64, 100000)
+
+			for i := range values {
+				values[i] = tc.generate()
+				sketch.Add(values[i])
+			}
+
+			sort.Float64s(values)
+
+			for _, q := range []float64{0, 0.5, 0.9, 0.95, 0.99, 0.999, 1} {
+				expected := exactQuantile(values, q)
+				assert.InDelta(t, expected, sketch.Quantile(q), expected*domain.DefaultSketchAccuracy+1e-9,
+					"Quantile %v is out of the error bound.", q)
+			}
+
+			assert.Equal(t, uint64(len(values)), sketch.Count, "Count mismatch.")
+			assert.LessOrEqual(t, len(sketch.Bins), domain.DefaultSketchBins, "Sketch exceeded its size bound.")
+		})
+	}
+}
+
+func TestQuantileSketch_Merge(t *testing.T) {
+	whole := domain.NewQuantileSketch()
+	left := domain.NewQuantileSketch()
+	right := domain.NewQuantileSketch()
+
+	for i := 0; i < 1000; i++ {
+		whole.Add(float64(i))
+
+		if i%2 == 0 {
+			left.Add(float64(i))
+		} else {
+			right.Add(float64(i))
+		}
+	}
+
+	left.Merge(right)
+
+	assert.Equal(t, whole.Count, left.Count, "Count mismatch.")
+	assert.Equal(t, whole.Min, left.Min, "Min mismatch.")
+	assert.Equal(t, whole.Max, left.Max, "Max mismatch.")
+	assert.Equal(t, whole.Bins, left.Bins, "Merged bins should equal the bins of the whole stream.")
+}
+
+func TestQuantileSketch_Empty(t *testing.T) {
+	sketch := domain.NewQuantileSketch()
+	assert.Zero(t, sketch.Quantile(0.95), "Empty sketch should return zero.")
+}
@@ -0,0 +1,80 @@
+package domain
+
+// ResourceStats aggregates the requests of a single resource.
+type ResourceStats struct {
+	Requests    int
+	Bytes       int
+	Sizes       *QuantileSketch // Distribution of response sizes for percentiles.
+	StatusCodes map[int]int
+	Methods     map[string]int
+	Latency     *LatencyStats // Latency of the requests whose lines carry timing; nil if none do.
+}
+
+// NewResourceStats creates empty resource statistics.
+func NewResourceStats() *ResourceStats {
+	return &ResourceStats{
+		Sizes:       NewQuantileSketch(),
+		StatusCodes: make(map[int]int),
+		Methods:     make(map[string]int),
+	}
+}
+
+// Add records a single request of the resource.
+func (s *ResourceStats) Add(logRecord *LogRecord) {
+	s.Requests++
+	s.Bytes += logRecord.ResponseSize
+	s.Sizes.Add(float64(logRecord.ResponseSize))
+	s.StatusCodes[logRecord.StatusCode]++
+
+	if logRecord.Method != "" {
+		s.Methods[logRecord.Method]++
+	}
+
+	if seconds, ok := logRecord.Latency(); ok {
+		if s.Latency == nil {
+			s.Latency = NewLatencyStats()
+		}
+
+		s.Latency.Add(seconds)
+	}
+}
+
+// Merge adds the requests recorded by other.
+func (s *ResourceStats) Merge(other *ResourceStats) {
+	s.Requests += other.Requests
+	s.Bytes += other.Bytes
+	s.Sizes.Merge(other.Sizes)
+
+	mergeCounts(s.StatusCodes, other.StatusCodes)
+	mergeCounts(s.Methods, other.Methods)
+
+	if other.Latency != nil {
+		if s.Latency == nil {
+			s.Latency = NewLatencyStats()
+		}
+
+		s.Latency.Merge(other.Latency)
+	}
+}
+
+// Errors returns the number of requests answered with an error status.
+func (s *ResourceStats) Errors() int {
+	errors := 0
+
+	for code, count := range s.StatusCodes {
+		if IsErrorStatus(code) {
+			errors += count
+		}
+	}
+
+	return errors
+}
+
+// ErrorRate returns the share of requests answered with an error status, or zero without requests.
+func (s *ResourceStats) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+
+	return float64(s.Errors()) / float64(s.Requests)
+}
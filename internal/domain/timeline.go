@@ -0,0 +1,277 @@
+package domain
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"time"
+)
+
+// MaxTimelineBuckets bounds the number of buckets of a timeline whose width is chosen automatically.
+const MaxTimelineBuckets = 60
+
+// MaxFixedTimelineBuckets bounds the number of buckets of a timeline with a fixed width that is reported;
+// wider spans are reported with Widened buckets.
+const MaxFixedTimelineBuckets = 1440
+
+// timelineWidths are the widths a timeline chooses from, each a multiple of the previous one, so
+// that buckets can be merged into wider ones without splitting any of them.
+var timelineWidths = []time.Duration{
+	time.Second, 5 * time.Second, 15 * time.Second, 30 * time.Second,
+	time.Minute, 5 * time.Minute, 15 * time.Minute, 30 * time.Minute,
+	time.Hour, 3 * time.Hour, 6 * time.Hour, 12 * time.Hour, 24 * time.Hour, 7 * 24 * time.Hour,
+}
+
+// TimeBucket aggregates the requests of one interval of a timeline.
+type TimeBucket struct {
+	Requests int
+	Bytes    int
+	Errors   int                 // Requests answered with a 4xx or 5xx status.
+	IPs      map[string]struct{} // Unique client IPs.
+}
+
+// merge adds the requests of other to b.
+func (b *TimeBucket) merge(other *TimeBucket) {
+	b.Requests += other.Requests
+	b.Bytes += other.Bytes
+	b.Errors += other.Errors
+
+	for ip := range other.IPs {
+		b.IPs[ip] = struct{}{}
+	}
+}
+
+// Timeline counts requests in buckets of equal width aligned to the Unix epoch. Unless the width is
+// fixed, it starts with one second and grows to the next of timelineWidths whenever the records span
+// more than MaxTimelineBuckets buckets, so the final width only depends on the time span of the records.
+type Timeline struct {
+	Width   time.Duration
+	Fixed   bool                  // Whether Width was set explicitly rather than chosen from the time span.
+	Buckets map[int64]*TimeBucket // Buckets by the Unix time of their start; empty buckets are left out.
+}
+
+// NewTimeline creates an empty timeline with buckets of the given width, or with an automatically
+// chosen width if it is zero.
+func NewTimeline(width time.Duration) *Timeline {
+	if width <= 0 {
+		return &Timeline{Width: timelineWidths[0], Buckets: make(map[int64]*TimeBucket)}
+	}
+
+	return &Timeline{Width: width, Fixed: true, Buckets: make(map[int64]*TimeBucket)}
+}
+
+// Add counts a request in the bucket of its timestamp. Requests without a timestamp are left out.
+func (t *Timeline) Add(timestamp time.Time, ip string, statusCode, bytes int) {
+	if timestamp.IsZero() {
+		return
+	}
+
+	bucket := t.bucket(timestamp.Unix())
+	bucket.Requests++
+	bucket.Bytes += bytes
+	bucket.IPs[ip] = struct{}{}
+
+	if IsErrorStatus(statusCode) {
+		bucket.Errors++
+	}
+}
+
+// SetWidth fixes the width of the buckets, merging the buckets collected so far into the new ones.
+func (t *Timeline) SetWidth(width time.Duration) {
+	if t.Fixed && t.Width == width {
+		return
+	}
+
+	t.Fixed = true
+	t.rebucket(width)
+}
+
+// Merge adds the buckets of other to t. A fixed width wins over an automatic one; otherwise the
+// buckets of the narrower timeline are merged into the wider ones.
+func (t *Timeline) Merge(other *Timeline) {
+	if other == nil || len(other.Buckets) == 0 {
+		return
+	}
+
+	switch {
+	case other.Fixed && (!t.Fixed || other.Width > t.Width):
+		t.Fixed = true
+		t.rebucket(other.Width)
+	case !t.Fixed && other.Width > t.Width:
+		t.rebucket(other.Width)
+	}
+
+	for start, bucket := range other.Buckets {
+		t.bucket(start).merge(bucket)
+	}
+}
+
+// Starts returns the start times of the buckets from the first to the last bucket holding requests,
+// including the empty buckets in between.
+func (t *Timeline) Starts() []time.Time {
+	if len(t.Buckets) == 0 {
+		return nil
+	}
+
+	width := int64(t.Width / time.Second)
+	keys := slices.Sorted(maps.Keys(t.Buckets))
+	starts := make([]time.Time, 0, (keys[len(keys)-1]-keys[0])/width+1)
+
+	for start := keys[0]; start <= keys[len(keys)-1]; start += width {
+		starts = append(starts, time.Unix(start, 0).UTC())
+	}
+
+	return starts
+}
+
+// Widened returns a copy of the timeline with its buckets merged into wider ones that span at most
+// maxBuckets, or the timeline itself if its buckets already do. The width is the narrowest wider one
+// of timelineWidths if the timeline uses one of them, or the narrowest multiple of its width otherwise.
+func (t *Timeline) Widened(maxBuckets int64) *Timeline {
+	if len(t.Buckets) == 0 || t.span() <= maxBuckets {
+		return t
+	}
+
+	widened := &Timeline{Width: t.widerWidth(maxBuckets), Fixed: true, Buckets: make(map[int64]*TimeBucket)}
+
+	for start, bucket := range t.Buckets {
+		widened.bucket(start).merge(bucket)
+	}
+
+	return widened
+}
+
+// widerWidth returns the width of the buckets of Widened.
+func (t *Timeline) widerWidth(maxBuckets int64) time.Duration {
+	first, last := t.bounds()
+
+	spanAt := func(width time.Duration) int64 {
+		widened := Timeline{Width: width}
+		return (widened.align(last)-widened.align(first))/int64(width/time.Second) + 1
+	}
+
+	if slices.Contains(timelineWidths, t.Width) {
+		for _, width := range timelineWidths {
+			if width > t.Width && spanAt(width) <= maxBuckets {
+				return width
+			}
+		}
+	}
+
+	factor := max(t.span()/maxBuckets, 2)
+	for spanAt(t.Width*time.Duration(factor)) > maxBuckets {
+		factor++
+	}
+
+	return t.Width * time.Duration(factor)
+}
+
+// Bucket returns the bucket starting at the given time, or an empty bucket if it holds no requests.
+func (t *Timeline) Bucket(start time.Time) TimeBucket {
+	if bucket, ok := t.Buckets[start.Unix()]; ok {
+		return *bucket
+	}
+
+	return TimeBucket{}
+}
+
+// Peak returns the start of the bucket with the most requests and its requests per second.
+// Ties go to the earliest bucket.
+func (t *Timeline) Peak() (time.Time, float64) {
+	var peakStart int64
+
+	peakRequests := 0
+
+	for _, start := range slices.Sorted(maps.Keys(t.Buckets)) {
+		if t.Buckets[start].Requests > peakRequests {
+			peakStart, peakRequests = start, t.Buckets[start].Requests
+		}
+	}
+
+	if peakRequests == 0 {
+		return time.Time{}, 0
+	}
+
+	return time.Unix(peakStart, 0).UTC(), float64(peakRequests) / t.Width.Seconds()
+}
+
+// bucket returns the bucket holding the given Unix time, creating it if needed.
+func (t *Timeline) bucket(unix int64) *TimeBucket {
+	start := t.align(unix)
+
+	bucket, ok := t.Buckets[start]
+	if ok {
+		return bucket
+	}
+
+	bucket = &TimeBucket{IPs: make(map[string]struct{})}
+	t.Buckets[start] = bucket
+
+	if !t.Fixed && t.span() > MaxTimelineBuckets {
+		t.grow()
+		return t.bucket(unix)
+	}
+
+	return bucket
+}
+
+// align returns the start of the bucket holding the given Unix time.
+func (t *Timeline) align(unix int64) int64 {
+	width := max(int64(t.Width/time.Second), 1)
+
+	start := unix - unix%width
+	if unix < 0 && unix%width != 0 {
+		start -= width
+	}
+
+	return start
+}
+
+// span returns the number of buckets from the first to the last one holding requests.
+func (t *Timeline) span() int64 {
+	first, last := t.bounds()
+
+	return (last-first)/int64(t.Width/time.Second) + 1
+}
+
+// bounds returns the starts of the first and the last bucket holding requests.
+func (t *Timeline) bounds() (first, last int64) {
+	first, last = int64(math.MaxInt64), int64(math.MinInt64)
+
+	for start := range t.Buckets {
+		first, last = min(first, start), max(last, start)
+	}
+
+	return first, last
+}
+
+// grow switches to the narrowest automatic width under which the buckets span at most MaxTimelineBuckets.
+func (t *Timeline) grow() {
+	for _, width := range timelineWidths {
+		if width <= t.Width {
+			continue
+		}
+
+		t.rebucket(width)
+
+		if t.span() <= MaxTimelineBuckets {
+			return
+		}
+	}
+}
+
+// rebucket merges the buckets into buckets of the given width.
+func (t *Timeline) rebucket(width time.Duration) {
+	buckets := t.Buckets
+	t.Width = width
+	t.Buckets = make(map[int64]*TimeBucket, len(buckets))
+
+	for start, bucket := range buckets {
+		aligned := t.align(start)
+		if merged, ok := t.Buckets[aligned]; ok {
+			merged.merge(bucket)
+		} else {
+			t.Buckets[aligned] = bucket
+		}
+	}
+}
@@ -0,0 +1,135 @@
+package domain_test
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTimeline_Add(t *testing.T) {
+	start := time.Date(2021, time.December, 12, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		width         time.Duration
+		span          time.Duration
+		expectedWidth time.Duration
+		expectedFixed bool
+	}{
+		{name: "Seconds for a short span", span: 30 * time.Second, expectedWidth: time.Second},
+		{name: "Minutes for an hour", span: time.Hour - time.Second, expectedWidth: time.Minute},
+		{name: "Half hours for a day", span: 24*time.Hour - time.Second, expectedWidth: 30 * time.Minute},
+		{name: "Fixed width", width: 5 * time.Minute, span: 24 * time.Hour, expectedWidth: 5 * time.Minute, expectedFixed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeline := domain.NewTimeline(tt.width)
+			timeline.Add(start, "10.0.0.1", 200, 100)
+			timeline.Add(start.Add(tt.span), "10.0.0.2", 500, 50)
+
+			assert.Equal(t, tt.expectedWidth, timeline.Width, "Width mismatch.")
+			assert.Equal(t, tt.expectedFixed, timeline.Fixed, "Fixed mismatch.")
+
+			starts := timeline.Starts()
+			assert.Equal(t, start, starts[0], "First bucket mismatch.")
+			assert.Equal(t, domain.TimeBucket{
+				Requests: 1,
+				Bytes:    100,
+				IPs:      map[string]struct{}{"10.0.0.1": {}},
+			}, timeline.Bucket(starts[0]), "First bucket content mismatch.")
+			assert.Equal(t, 1, timeline.Bucket(starts[len(starts)-1]).Errors, "Errors of the last bucket mismatch.")
+		})
+	}
+}
+
+func TestTimeline_Peak(t *testing.T) {
+	start := time.Date(2021, time.December, 12, 15, 0, 0, 0, time.UTC)
+	timeline := domain.NewTimeline(time.Minute)
+
+	timeline.Add(start, "10.0.0.1", 200, 0)
+
+	for i := range 30 {
+		timeline.Add(start.Add(2*time.Minute+time.Duration(i)*time.Second), "10.0.0.1", 200, 0)
+	}
+
+	peakTime, peakRPS := timeline.Peak()
+	assert.Equal(t, start.Add(2*time.Minute), peakTime, "Peak time mismatch.")
+	assert.InDelta(t, 0.5, peakRPS, 1e-9, "Peak RPS mismatch.")
+	assert.Len(t, timeline.Starts(), 3, "Empty buckets between requests should be listed.")
+	assert.Zero(t, timeline.Bucket(start.Add(time.Minute)).Requests, "The bucket without requests should be empty.")
+}
+
+func TestTimeline_MergeMatchesSingle(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	start := time.Date(2021, time.December, 12, 0, 0, 0, 0, time.UTC)
+
+	for _, width := range []time.Duration{0, 10 * time.Minute} {
+		single := domain.NewTimeline(width)
+		parts := []*domain.Timeline{domain.NewTimeline(width), domain.NewTimeline(width), domain.NewTimeline(width)}
+
+		for i := range 1000 {
+			// The parts cover spans of different lengths, so they choose different widths.
+			timestamp := start.Add(time.Duration(rng.Int63n(int64(time.Hour) << (i % 3))))
+			single.Add(timestamp, "10.0.0.1", 200+100*(i%4), i)
+			parts[i%3].Add(timestamp, "10.0.0.1", 200+100*(i%4), i)
+		}
+
+		merged := domain.NewTimeline(0)
+		for _, part := range parts {
+			merged.Merge(part)
+		}
+
+		assert.Equal(t, single, merged, "Merged timeline with width %s differs from a single pass.", width)
+	}
+}
+
+func TestTimeline_Widened(t *testing.T) {
+	start := time.Date(2021, time.December, 12, 15, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name          string
+		width         time.Duration
+		span          time.Duration
+		expectedWidth time.Duration
+	}{
+		{name: "Buckets that fit", width: time.Minute, span: 23 * time.Hour, expectedWidth: time.Minute},
+		{name: "Wider automatic width", width: time.Minute, span: 48 * time.Hour, expectedWidth: 5 * time.Minute},
+		{name: "Multiple of a custom width", width: 7 * time.Minute, span: 30 * 24 * time.Hour, expectedWidth: 35 * time.Minute},
+		{name: "Multiple of a week", width: 7 * 24 * time.Hour, span: 100 * 365 * 24 * time.Hour, expectedWidth: 4 * 7 * 24 * time.Hour},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			timeline := domain.NewTimeline(tt.width)
+			timeline.Add(start, "10.0.0.1", 200, 100)
+			timeline.Add(start.Add(tt.span), "10.0.0.2", 500, 50)
+
+			widened := timeline.Widened(domain.MaxFixedTimelineBuckets)
+			assert.Equal(t, tt.expectedWidth, widened.Width, "Width mismatch.")
+			assert.LessOrEqual(t, len(widened.Starts()), domain.MaxFixedTimelineBuckets, "Buckets mismatch.")
+			assert.Equal(t, tt.width, timeline.Width, "The timeline itself should be left as it is.")
+
+			var requests, errors int
+
+			for _, bucketStart := range widened.Starts() {
+				requests += widened.Bucket(bucketStart).Requests
+				errors += widened.Bucket(bucketStart).Errors
+			}
+
+			assert.Equal(t, 2, requests, "Requests mismatch.")
+			assert.Equal(t, 1, errors, "Errors mismatch.")
+		})
+	}
+}
+
+func TestTimeline_AddWithoutTimestamp(t *testing.T) {
+	timeline := domain.NewTimeline(time.Minute)
+	timeline.Add(time.Time{}, "10.0.0.1", 200, 100)
+	timeline.Add(time.Date(2021, time.December, 12, 15, 0, 0, 0, time.UTC), "10.0.0.2", 200, 100)
+
+	assert.Len(t, timeline.Starts(), 1, "Requests without a timestamp should be left out.")
+}
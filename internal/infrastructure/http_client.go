@@ -0,0 +1,83 @@
+package infrastructure
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// NewHTTPClient creates a client for downloading URL sources. certFile and keyFile name a PEM
+// client certificate for mutual TLS, caFile a PEM bundle of extra trusted authorities; each is optional.
+func NewHTTPClient(certFile, keyFile, caFile string) (*http.Client, error) {
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", caFile)
+		}
+
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+
+	return &http.Client{Transport: transport}, nil
+}
+
+// ParseHTTPHeaders builds the headers sent with every download from "Name: value" strings,
+// a bearer token and "user:password" basic auth credentials. Empty values are ignored.
+func ParseHTTPHeaders(headers []string, bearerToken, basicAuth string) (http.Header, error) {
+	result := make(http.Header)
+
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected \"Name: value\"", header)
+		}
+
+		result.Add(textproto.TrimString(name), textproto.TrimString(value))
+	}
+
+	if bearerToken != "" && basicAuth != "" {
+		return nil, fmt.Errorf("bearer token and basic auth cannot be used together")
+	}
+
+	if bearerToken != "" {
+		result.Set("Authorization", "Bearer "+bearerToken)
+	}
+
+	if basicAuth != "" {
+		if !strings.Contains(basicAuth, ":") {
+			return nil, fmt.Errorf("invalid basic auth credentials, expected \"user:password\"")
+		}
+
+		result.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(basicAuth)))
+	}
+
+	return result, nil
+}
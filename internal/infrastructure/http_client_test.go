@@ -0,0 +1,101 @@
+package infrastructure_test
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abakunov/log-analyzer/internal/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHTTPHeaders(t *testing.T) {
+	tests := []struct {
+		name        string
+		headers     []string
+		bearerToken string
+		basicAuth   string
+		expected    http.Header
+		expectErr   bool
+	}{
+		{
+			name:     "Custom headers",
+			headers:  []string{"X-Tenant: edge", "accept-encoding:identity"},
+			expected: http.Header{"X-Tenant": {"edge"}, "Accept-Encoding": {"identity"}},
+		},
+		{
+			name:        "Bearer token",
+			bearerToken: "secret",
+			expected:    http.Header{"Authorization": {"Bearer secret"}},
+		},
+		{
+			name:      "Basic auth",
+			basicAuth: "user:pass",
+			expected:  http.Header{"Authorization": {"Basic dXNlcjpwYXNz"}},
+		},
+		{
+			name:      "Header without a colon",
+			headers:   []string{"X-Tenant"},
+			expectErr: true,
+		},
+		{
+			name:      "Basic auth without a password",
+			basicAuth: "user",
+			expectErr: true,
+		},
+		{
+			name:        "Bearer token with basic auth",
+			bearerToken: "secret",
+			basicAuth:   "user:pass",
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := infrastructure.ParseHTTPHeaders(tt.headers, tt.bearerToken, tt.basicAuth)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestNewHTTPClient_CustomCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, caPEM, 0o600))
+
+	client, err := infrastructure.NewHTTPClient("", "", caFile)
+	require.NoError(t, err)
+
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err, "Server certificate should be trusted through the CA file.")
+	resp.Body.Close()
+
+	client, err = infrastructure.NewHTTPClient("", "", "")
+	require.NoError(t, err)
+
+	resp, err = client.Get(server.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+
+	assert.Error(t, err, "Server certificate should not be trusted without the CA file.")
+
+	_, err = infrastructure.NewHTTPClient("missing.pem", "missing.key", "")
+	assert.Error(t, err, "Missing client certificate should be reported.")
+}
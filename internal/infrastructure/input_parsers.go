@@ -2,7 +2,6 @@ package infrastructure
 
 import (
 	"fmt"
-	"path/filepath"
 	"time"
 
 	"github.com/abakunov/log-analyzer/internal/application"
@@ -51,21 +50,44 @@ func parseTimeWithFormats(input string, formats []string) (time.Time, error) {
 
 // ParseFiles parses the file path or URL pattern into a list of paths.
 func ParseFiles(pattern string) ([]string, error) {
-	// Check if the path is a URL.
-	if application.IsURL(pattern) {
-		return []string{pattern}, nil
-	}
+	return ParsePaths([]string{pattern}, application.SourceFilter{})
+}
+
+// ParsePaths parses file path and URL patterns into a list of paths, each listed once. Local patterns
+// may contain "**" to match any number of directories; the filter leaves out excluded and hidden matches.
+// The rotated files of a log are ordered from the oldest to the newest.
+func ParsePaths(patterns []string, filter application.SourceFilter) ([]string, error) {
+	var paths []string
 
-	// Use filepath.Glob for local files.
-	files, err := filepath.Glob(pattern)
-	if err != nil {
-		return nil, fmt.Errorf("error finding files: %v", err)
+	seen := make(map[string]bool)
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
 	}
 
-	// Check if files were found.
-	if len(files) == 0 {
-		return nil, fmt.Errorf("no files found matching the pattern: %s", pattern)
+	for _, pattern := range patterns {
+		// Check if the path is a URL, an S3 path or the standard input.
+		if application.IsURL(pattern) || application.IsS3(pattern) || pattern == application.StdinPath {
+			add(pattern)
+			continue
+		}
+
+		files, err := filter.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("error finding files: %v", err)
+		}
+
+		// Check if files were found.
+		if len(files) == 0 {
+			return nil, fmt.Errorf("no files found matching the pattern: %s", pattern)
+		}
+
+		for _, file := range files {
+			add(file)
+		}
 	}
 
-	return files, nil
+	return application.OrderRotated(paths), nil
 }
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/abakunov/log-analyzer/internal/application"
 	"github.com/abakunov/log-analyzer/internal/infrastructure"
 	"github.com/stretchr/testify/assert"
 )
@@ -97,6 +98,12 @@ func TestParseFiles(t *testing.T) {
 			expected:  []string{"http://example.com/file.log"},
 			expectErr: false,
 		},
+		{
+			name:      "Standard input",
+			pattern:   "-",
+			expected:  []string{"-"},
+			expectErr: false,
+		},
 	}
 
 	for _, tc := range testCases {
@@ -119,6 +126,56 @@ func TestParseFiles(t *testing.T) {
 	}
 }
 
+func TestParsePaths(t *testing.T) {
+	mockFiles := []string{"testdata/nginx/access.log", "testdata/nginx/old/access.log.1", "testdata/nginx/.access.log.swp"}
+	assert.NoError(t, setupMockFiles(mockFiles), "Failed to set up mock files.")
+	defer cleanupMockFiles(mockFiles)
+
+	testCases := []struct {
+		name      string
+		patterns  []string
+		filter    application.SourceFilter
+		expected  []string
+		expectErr bool
+	}{
+		{
+			name:     "Several patterns",
+			patterns: []string{"testdata/nginx/*.log", "-", "s3://logs/access.log"},
+			expected: []string{"testdata/nginx/access.log", "-", "s3://logs/access.log"},
+		},
+		{
+			name:     "Overlapping patterns",
+			patterns: []string{"testdata/**/access.log*", "testdata/nginx/access.log"},
+			filter:   application.SourceFilter{SkipHidden: true},
+			expected: []string{"testdata/nginx/access.log", "testdata/nginx/old/access.log.1"},
+		},
+		{
+			name:     "Excluded directory",
+			patterns: []string{"testdata/**/*.log*"},
+			filter:   application.SourceFilter{Exclude: []string{"old", "*.swp"}},
+			expected: []string{"testdata/nginx/access.log"},
+		},
+		{
+			name:      "Pattern without matches",
+			patterns:  []string{"testdata/nginx/*.log", "testdata/apache/*.log"},
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			paths, err := infrastructure.ParsePaths(tc.patterns, tc.filter)
+			if tc.expectErr {
+				assert.Error(t, err, "Expected an error, but got none.")
+				return
+			}
+
+			assert.NoError(t, err, "Did not expect an error, but got one.")
+			assert.Equal(t, tc.expected, paths, "Path list mismatch.")
+		})
+	}
+}
+
 // Helper functions for mock file setup and cleanup.
 func setupMockFiles(files []string) error {
 	for _, file := range files {
@@ -0,0 +1,109 @@
+package infrastructure
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+)
+
+// ErrLogFormatNotFound is returned when the nginx configuration has no log_format with the requested name.
+var ErrLogFormatNotFound = errors.New("log_format not found in nginx config")
+
+// LoadNginxLogFormat reads an nginx.conf file and returns the log_format string registered under the given name.
+func LoadNginxLogFormat(confPath, name string) (string, error) {
+	data, err := os.ReadFile(confPath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read nginx config %s: %w", confPath, err)
+	}
+
+	return ExtractNginxLogFormat(string(data), name)
+}
+
+// ExtractNginxLogFormat finds the log_format directive with the given name in the nginx configuration text.
+// Quoted parts of the directive are concatenated the same way NGINX does it.
+func ExtractNginxLogFormat(conf, name string) (string, error) {
+	tokens := tokenizeNginxConfig(conf)
+
+	for i := 0; i < len(tokens); i++ {
+		if tokens[i] != "log_format" || i+1 >= len(tokens) || tokens[i+1] != name {
+			continue
+		}
+
+		var sb strings.Builder
+
+		for _, tok := range tokens[i+2:] {
+			if tok == ";" {
+				return sb.String(), nil
+			}
+
+			if strings.HasPrefix(tok, "escape=") {
+				continue
+			}
+
+			sb.WriteString(tok)
+		}
+
+		return "", fmt.Errorf("unterminated log_format %q directive", name)
+	}
+
+	return "", fmt.Errorf("%w: %q", ErrLogFormatNotFound, name)
+}
+
+// tokenizeNginxConfig splits the configuration into words, unquoted strings and the ";", "{", "}" separators.
+// Comments are dropped.
+func tokenizeNginxConfig(conf string) []string {
+	var (
+		tokens []string
+		word   strings.Builder
+	)
+
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, word.String())
+			word.Reset()
+		}
+	}
+
+	runes := []rune(conf)
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		switch {
+		case r == '#':
+			flush()
+
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '\'' || r == '"':
+			flush()
+
+			var quoted strings.Builder
+
+			for i++; i < len(runes) && runes[i] != r; i++ {
+				if runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == r {
+					i++
+				}
+
+				quoted.WriteRune(runes[i])
+			}
+
+			tokens = append(tokens, quoted.String())
+		case r == ';' || r == '{' || r == '}':
+			flush()
+
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			word.WriteRune(r)
+		}
+	}
+
+	flush()
+
+	return tokens
+}
@@ -0,0 +1,86 @@
+package infrastructure_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abakunov/log-analyzer/internal/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExtractNginxLogFormat(t *testing.T) {
+	conf := `
+http {
+    # log_format commented '$remote_addr';
+    log_format  main  '$remote_addr - $remote_user [$time_local] "$request" '
+                      '$status $body_bytes_sent "$http_referer" '
+                      '"$http_user_agent" "$http_x_forwarded_for"';
+
+    log_format timed escape=json '$host $request_time';
+
+    access_log  /var/log/nginx/access.log  main;
+}
+`
+
+	testCases := []struct {
+		name      string
+		format    string
+		expected  string
+		expectErr bool
+	}{
+		{
+			name:   "Multi-line format",
+			format: "main",
+			expected: `$remote_addr - $remote_user [$time_local] "$request" ` +
+				`$status $body_bytes_sent "$http_referer" "$http_user_agent" "$http_x_forwarded_for"`,
+			expectErr: false,
+		},
+		{
+			name:      "Format with escape parameter",
+			format:    "timed",
+			expected:  `$host $request_time`,
+			expectErr: false,
+		},
+		{
+			name:      "Commented out format",
+			format:    "commented",
+			expectErr: true,
+		},
+		{
+			name:      "Missing format",
+			format:    "missing",
+			expectErr: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			format, err := infrastructure.ExtractNginxLogFormat(conf, tc.format)
+
+			if tc.expectErr {
+				assert.Error(t, err, "Expected an error, but got none.")
+			} else {
+				assert.NoError(t, err, "Did not expect an error, but got one.")
+				assert.Equal(t, tc.expected, format, "Format mismatch.")
+			}
+		})
+	}
+}
+
+func TestLoadNginxLogFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nginx.conf")
+	require.NoError(t, os.WriteFile(path, []byte("log_format main '$remote_addr';"), 0o600))
+
+	format, err := infrastructure.LoadNginxLogFormat(path, "main")
+	require.NoError(t, err)
+	assert.Equal(t, "$remote_addr", format, "Format mismatch.")
+
+	_, err = infrastructure.LoadNginxLogFormat(path, "combined")
+	assert.ErrorIs(t, err, infrastructure.ErrLogFormatNotFound, "A missing format should be reported as not found.")
+
+	_, err = infrastructure.LoadNginxLogFormat(path+".missing", "combined")
+	assert.Error(t, err, "A missing config should be reported.")
+	assert.NotErrorIs(t, err, infrastructure.ErrLogFormatNotFound, "A missing config should not look like a missing format.")
+}
@@ -1,15 +1,42 @@
 package infrastructure
 
 import (
+	"cmp"
 	"fmt"
+	"maps"
 	"math"
+	"slices"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/abakunov/log-analyzer/internal/domain"
 )
 
+// reportPercentiles are the percentiles shown in the response size percentiles section.
+var reportPercentiles = []float64{50, 90, 95, 99, 99.9}
+
+// timelineTimeLayout is the layout of the bucket times of the timeline.
+const timelineTimeLayout = "02.01.2006 15:04:05"
+
+// sparklineLevels are the characters of the request sparkline, from the fewest requests to the most.
+const sparklineLevels = "_.-:=+*#%@"
+
+// latencyPercentiles are the percentiles shown for request latencies.
+var latencyPercentiles = []float64{50, 90, 99}
+
+const (
+	// topUsersLimit is the number of rows shown in the authenticated users table.
+	topUsersLimit = 10
+	// topResourcesDetailLimit is the number of rows shown in the top resources detail table.
+	topResourcesDetailLimit = 10
+	// topClientsLimit is the number of rows shown in each of the top clients tables.
+	topClientsLimit = 10
+	// slowestResourcesLimit is the number of rows shown in the slowest resources table.
+	slowestResourcesLimit = 10
+)
+
 // ReportFormatter is responsible for generating text reports.
 type ReportFormatter struct {
 	Metrics *domain.Metrics
@@ -70,29 +97,225 @@ func (rf *ReportFormatter) Render(format string) string {
 	// Add report creation timestamp.
 	addHeader(&sb, format, fmt.Sprintf("Report created: %s", time.Now().Format("02.01.2006 15:04:05")))
 
-	// Add general information section.
-	addTable(&sb, format, "General Information", [][]string{
+	rf.addGeneralInformation(&sb, format)
+	rf.addSizePercentiles(&sb, format)
+	rf.addTimeline(&sb, format)
+	rf.addSourceFormats(&sb, format)
+	rf.addResources(&sb, format)
+	rf.addResourceDetails(&sb, format)
+	rf.addStatusCodes(&sb, format)
+	rf.addTimings(&sb, format)
+	rf.addLatency(&sb, format)
+	rf.addUsers(&sb, format)
+	rf.addClients(&sb, format)
+
+	return sb.String()
+}
+
+// addGeneralInformation adds the general information section.
+func (rf *ReportFormatter) addGeneralInformation(sb *strings.Builder, format string) {
+	addTable(sb, format, "General Information", [][]string{
 		{"Files", strings.Join(rf.Metrics.FileNames, ", ")},
 		{"Start Date", rf.Metrics.StartDate.Format("02.01.2006")},
 		{"End Date", rf.Metrics.EndDate.Format("02.01.2006")},
 		{"Total Requests", fmt.Sprintf("%d", rf.Metrics.TotalRequests)},
 		{"Unique IPs Count", fmt.Sprintf("%d", len(rf.Metrics.UniqueIPs))},
 		{"RPS (Requests/sec)", fmt.Sprintf("%.2f", rf.Metrics.RPS)},
+		{"Peak RPS", rf.peakRPS()},
 		{"Average Response Size", fmt.Sprintf("%db", int(math.Round(rf.Metrics.AverageRespSize)))},
-		{"95th Percentile Size", fmt.Sprintf("%db", rf.Metrics.Percentile95)},
+		{"95th Percentile Size", fmt.Sprintf("%db", rf.sizePercentile(95))},
 	})
+}
+
+// peakRPS describes the requests per second of the busiest timeline bucket and when it started.
+func (rf *ReportFormatter) peakRPS() string {
+	peakTime, rps := rf.Metrics.Timeline.Peak()
+	if peakTime.IsZero() {
+		return "-"
+	}
+
+	return fmt.Sprintf("%.2f at %s (%s bucket)", rps, peakTime.Format(timelineTimeLayout), formatWidth(rf.Metrics.Timeline.Width))
+}
+
+// addSizePercentiles adds the response size percentiles section.
+func (rf *ReportFormatter) addSizePercentiles(sb *strings.Builder, format string) {
+	percentilesTable := [][]string{{"Percentile", "Size"}}
+	for _, percentile := range reportPercentiles {
+		percentilesTable = append(percentilesTable, []string{
+			fmt.Sprintf("p%s", strconv.FormatFloat(percentile, 'f', -1, 64)),
+			fmt.Sprintf("%db", rf.sizePercentile(percentile)),
+		})
+	}
+
+	addTable(sb, format, "Response Size Percentiles", percentilesTable)
+}
+
+// sizePercentile returns the estimated response size at the given percentile.
+func (rf *ReportFormatter) sizePercentile(percentile float64) int {
+	return int(math.Round(rf.Metrics.ResponseSizes.Quantile(percentile / 100)))
+}
+
+// addTimeline adds the requests, bytes, errors and unique IPs of every timeline bucket and, in plain
+// text, a sparkline of the requests, if any request was counted. Buckets of a fixed width spanning
+// more than domain.MaxFixedTimelineBuckets are merged into wider ones.
+func (rf *ReportFormatter) addTimeline(sb *strings.Builder, format string) {
+	timeline := rf.Metrics.Timeline.Widened(domain.MaxFixedTimelineBuckets)
+
+	starts := timeline.Starts()
+	if len(starts) == 0 {
+		return
+	}
+
+	counts := make([]int, len(starts))
+	timelineTable := [][]string{{"Time", "Requests", "Bytes", "Errors", "Unique IPs"}}
+
+	for i, start := range starts {
+		bucket := timeline.Bucket(start)
+		counts[i] = bucket.Requests
+		timelineTable = append(timelineTable, []string{
+			start.Format(timelineTimeLayout),
+			fmt.Sprintf("%d", bucket.Requests),
+			fmt.Sprintf("%db", bucket.Bytes),
+			fmt.Sprintf("%d", bucket.Errors),
+			fmt.Sprintf("%d", len(bucket.IPs)),
+		})
+	}
+
+	title := fmt.Sprintf("Timeline (%s buckets)", formatWidth(timeline.Width))
+	if timeline != rf.Metrics.Timeline {
+		title = fmt.Sprintf("Timeline (%s buckets, widened from %s)", formatWidth(timeline.Width), formatWidth(rf.Metrics.Timeline.Width))
+	}
+
+	addTable(sb, format, title, timelineTable)
+
+	if format != "markdown" && format != "adoc" {
+		end := starts[len(starts)-1].Add(timeline.Width)
+		fmt.Fprintf(sb, "Requests per %s:\n %s |%s| %s\n\n", formatWidth(timeline.Width),
+			starts[0].Format(timelineTimeLayout), sparkline(counts), end.Format(timelineTimeLayout))
+	}
+}
+
+// sparkline draws the values as a line of ASCII characters from the lowest to the highest level,
+// with a space for zero.
+func sparkline(values []int) string {
+	peak := slices.Max(values)
+	line := make([]byte, len(values))
+
+	for i, value := range values {
+		switch {
+		case value == 0:
+			line[i] = ' '
+		default:
+			line[i] = sparklineLevels[(value*(len(sparklineLevels)-1)+peak-1)/peak]
+		}
+	}
 
-	// Add resources section.
-	sortedResources := sortMapByValue(rf.Metrics.Resources)
+	return string(line)
+}
+
+// formatWidth formats a bucket width without zero minutes and seconds, such as 5m or 1h.
+func formatWidth(width time.Duration) string {
+	text := width.String()
+	if strings.HasSuffix(text, "m0s") {
+		text = strings.TrimSuffix(text, "0s")
+	}
+
+	if strings.HasSuffix(text, "h0m") {
+		text = strings.TrimSuffix(text, "0m")
+	}
+
+	return text
+}
+
+// addSourceFormats adds the detected log format of every source, if formats were detected.
+func (rf *ReportFormatter) addSourceFormats(sb *strings.Builder, format string) {
+	if len(rf.Metrics.SourceFormats) == 0 {
+		return
+	}
 
+	formatsTable := [][]string{{"Source", "Format"}}
+	for _, source := range slices.Sorted(maps.Keys(rf.Metrics.SourceFormats)) {
+		formatsTable = append(formatsTable, []string{source, rf.Metrics.SourceFormats[source]})
+	}
+
+	addTable(sb, format, "Log Formats", formatsTable)
+}
+
+// addResources adds the requested resources section.
+func (rf *ReportFormatter) addResources(sb *strings.Builder, format string) {
 	resourcesTable := [][]string{{"Resource", "Count"}}
-	for _, res := range sortedResources {
-		resourcesTable = append(resourcesTable, []string{res.Key, fmt.Sprintf("%d", res.Value)})
+	for _, resource := range rf.resourcesByRequests() {
+		resourcesTable = append(resourcesTable, []string{resource, fmt.Sprintf("%d", rf.Metrics.ResourceStats[resource].Requests)})
 	}
 
-	addTable(&sb, format, "Requested Resources", resourcesTable)
+	addTable(sb, format, "Requested Resources", resourcesTable)
+}
+
+// addResourceDetails adds the methods, status codes, error rate, sizes and latency of the most requested resources.
+func (rf *ReportFormatter) addResourceDetails(sb *strings.Builder, format string) {
+	resources := rf.resourcesByRequests()
+	if len(resources) > topResourcesDetailLimit {
+		resources = resources[:topResourcesDetailLimit]
+	}
+
+	detailsTable := [][]string{{"Resource", "Count", "Methods", "Codes", "Error Rate", "Bytes", "p95 Size", "Avg Latency", "p95 Latency"}}
+
+	for _, resource := range resources {
+		stats := rf.Metrics.ResourceStats[resource]
 
-	// Add status codes section.
+		averageLatency, p95Latency := "-", "-"
+		if stats.Latency != nil {
+			averageLatency = formatSeconds(stats.Latency.Average())
+			p95Latency = formatSeconds(stats.Latency.Durations.Quantile(0.95))
+		}
+
+		detailsTable = append(detailsTable, []string{
+			resource,
+			fmt.Sprintf("%d", stats.Requests),
+			formatCounts(sortMapByValue(stats.Methods)),
+			formatCounts(sortIntMapByValue(stats.StatusCodes)),
+			fmt.Sprintf("%.1f%%", stats.ErrorRate()*100),
+			fmt.Sprintf("%db", stats.Bytes),
+			fmt.Sprintf("%db", int(math.Round(stats.Sizes.Quantile(0.95)))),
+			averageLatency,
+			p95Latency,
+		})
+	}
+
+	addTable(sb, format, "Top Resources Detail", detailsTable)
+}
+
+// resourcesByRequests returns the resources from the most to the least requested, and by name for
+// the same number of requests.
+func (rf *ReportFormatter) resourcesByRequests() []string {
+	resources := slices.Collect(maps.Keys(rf.Metrics.ResourceStats))
+
+	slices.SortFunc(resources, func(a, b string) int {
+		if c := cmp.Compare(rf.Metrics.ResourceStats[b].Requests, rf.Metrics.ResourceStats[a].Requests); c != 0 {
+			return c
+		}
+
+		return strings.Compare(a, b)
+	})
+
+	return resources
+}
+
+// formatCounts formats counters sorted by value, such as methods, as "GET 90, POST 10".
+func formatCounts[K comparable](pairs []struct {
+	Key   K
+	Value int
+}) string {
+	parts := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		parts = append(parts, fmt.Sprintf("%v %d", pair.Key, pair.Value))
+	}
+
+	return strings.Join(parts, ", ")
+}
+
+// addStatusCodes adds the response codes section.
+func (rf *ReportFormatter) addStatusCodes(sb *strings.Builder, format string) {
 	sortedStatusCodes := sortIntMapByValue(rf.Metrics.StatusCodes)
 
 	statusTable := [][]string{{"Code", "Count"}}
@@ -100,9 +323,174 @@ func (rf *ReportFormatter) Render(format string) string {
 		statusTable = append(statusTable, []string{fmt.Sprintf("%d", code.Key), fmt.Sprintf("%d", code.Value)})
 	}
 
-	addTable(&sb, format, "Response Codes", statusTable)
+	addTable(sb, format, "Response Codes", statusTable)
+}
 
-	return sb.String()
+// addTimings adds the processing times reported by the log format, if there are any.
+func (rf *ReportFormatter) addTimings(sb *strings.Builder, format string) {
+	if len(rf.Metrics.Timings) == 0 {
+		return
+	}
+
+	timingsTable := [][]string{{"Timing", "Count", "Average", "Max"}}
+
+	for _, name := range slices.Sorted(maps.Keys(rf.Metrics.Timings)) {
+		stats := rf.Metrics.Timings[name]
+		timingsTable = append(timingsTable, []string{
+			name,
+			fmt.Sprintf("%d", stats.Count),
+			formatSeconds(stats.Total / float64(stats.Count)),
+			formatSeconds(stats.Max),
+		})
+	}
+
+	addTable(sb, format, "Processing Times", timingsTable)
+}
+
+// addLatency adds the request latencies overall, per resource and for the slowest resources, if
+// the log format carries request or upstream response times.
+func (rf *ReportFormatter) addLatency(sb *strings.Builder, format string) {
+	if rf.Metrics.Latency.Count == 0 {
+		return
+	}
+
+	latencyTable := [][]string{
+		{"Statistic", "Value"},
+		{"Timed Requests", fmt.Sprintf("%d", rf.Metrics.Latency.Count)},
+		{"Requests Without Timing", fmt.Sprintf("%d", rf.Metrics.UntimedRequests)},
+		{"Average", formatSeconds(rf.Metrics.Latency.Average())},
+	}
+
+	for _, percentile := range latencyPercentiles {
+		latencyTable = append(latencyTable, []string{
+			fmt.Sprintf("p%s", strconv.FormatFloat(percentile, 'f', -1, 64)),
+			formatSeconds(rf.Metrics.Latency.Durations.Quantile(percentile / 100)),
+		})
+	}
+
+	latencyTable = append(latencyTable, []string{"Max", formatSeconds(rf.Metrics.Latency.Max)})
+	addTable(sb, format, "Latency", latencyTable)
+
+	var resources []string
+
+	for _, resource := range rf.resourcesByRequests() {
+		if rf.resourceLatency(resource) != nil {
+			resources = append(resources, resource)
+		}
+	}
+
+	addTable(sb, format, "Latency by Resource", rf.latencyRows(resources))
+
+	// The slowest resources are the ones with the highest p99, so that one slow request does not
+	// outweigh a resource that is consistently slow.
+	slices.SortStableFunc(resources, func(a, b string) int {
+		return cmp.Compare(rf.resourceLatency(b).Durations.Quantile(0.99), rf.resourceLatency(a).Durations.Quantile(0.99))
+	})
+
+	if len(resources) > slowestResourcesLimit {
+		resources = resources[:slowestResourcesLimit]
+	}
+
+	addTable(sb, format, "Slowest Resources", rf.latencyRows(resources))
+}
+
+// latencyRows returns a table of the latency statistics of the resources.
+func (rf *ReportFormatter) latencyRows(resources []string) [][]string {
+	header := []string{"Resource", "Count", "Average"}
+	for _, percentile := range latencyPercentiles {
+		header = append(header, fmt.Sprintf("p%s", strconv.FormatFloat(percentile, 'f', -1, 64)))
+	}
+
+	rows := [][]string{append(header, "Max")}
+
+	for _, resource := range resources {
+		stats := rf.resourceLatency(resource)
+		row := []string{resource, fmt.Sprintf("%d", stats.Count), formatSeconds(stats.Average())}
+
+		for _, percentile := range latencyPercentiles {
+			row = append(row, formatSeconds(stats.Durations.Quantile(percentile/100)))
+		}
+
+		rows = append(rows, append(row, formatSeconds(stats.Max)))
+	}
+
+	return rows
+}
+
+// resourceLatency returns the latency statistics of a resource, or nil if none of its lines carry timing.
+func (rf *ReportFormatter) resourceLatency(resource string) *domain.LatencyStats {
+	if stats, ok := rf.Metrics.ResourceStats[resource]; ok {
+		return stats.Latency
+	}
+
+	return nil
+}
+
+// formatSeconds formats a duration in seconds with millisecond precision, as NGINX logs it.
+func formatSeconds(seconds float64) string {
+	return fmt.Sprintf("%.3fs", seconds)
+}
+
+// addUsers adds the most active authenticated users, if the logs contain any.
+func (rf *ReportFormatter) addUsers(sb *strings.Builder, format string) {
+	if len(rf.Metrics.Users) == 0 {
+		return
+	}
+
+	sortedUsers := sortMapByValue(rf.Metrics.Users)
+	if len(sortedUsers) > topUsersLimit {
+		sortedUsers = sortedUsers[:topUsersLimit]
+	}
+
+	usersTable := [][]string{{"User", "Count"}}
+	for _, user := range sortedUsers {
+		usersTable = append(usersTable, []string{user.Key, fmt.Sprintf("%d", user.Value)})
+	}
+
+	addTable(sb, format, "Top Authenticated Users", usersTable)
+}
+
+// addClients adds the clients with the most requests and the most bytes, and the busiest subnets.
+func (rf *ReportFormatter) addClients(sb *strings.Builder, format string) {
+	clients := rf.Metrics.Clients
+	if len(clients) == 0 {
+		return
+	}
+
+	byRequests := func(stats *domain.ClientStats) int { return stats.Requests }
+	byBytes := func(stats *domain.ClientStats) int { return stats.Bytes }
+
+	addTable(sb, format, "Top Clients by Requests", clientRows(clients, byRequests))
+	addTable(sb, format, "Top Clients by Bytes", clientRows(clients, byBytes))
+	addTable(sb, format, "Top Subnets (/24, IPv6 /48)", clientRows(domain.RollupClients(clients, 24, 48), byRequests))
+	addTable(sb, format, "Top Subnets (/16)", clientRows(domain.RollupClients(clients, 16, 0), byRequests))
+}
+
+// clientRows returns a table of the topClientsLimit clients with the highest value of the key,
+// with ties broken by client, and their requests, bytes, error rate and distinct resources.
+func clientRows(clients map[string]*domain.ClientStats, key func(*domain.ClientStats) int) [][]string {
+	sorted := slices.SortedFunc(maps.Keys(clients), func(a, b string) int {
+		return cmp.Or(cmp.Compare(key(clients[b]), key(clients[a])), strings.Compare(a, b))
+	})
+
+	if len(sorted) > topClientsLimit {
+		sorted = sorted[:topClientsLimit]
+	}
+
+	rows := [][]string{{"Client", "Requests", "Bytes", "Error Rate", "Distinct URLs"}}
+
+	for _, client := range sorted {
+		stats := clients[client]
+		rows = append(rows, []string{
+			client,
+			fmt.Sprintf("%d", stats.Requests),
+			fmt.Sprintf("%db", stats.Bytes),
+			fmt.Sprintf("%.1f%%", stats.ErrorRate()*100),
+			fmt.Sprintf("%d", len(stats.Resources)),
+		})
+	}
+
+	return rows
 }
 
 // addHeader adds a section header to the report in the specified format.
@@ -147,7 +535,12 @@ func addTable(sb *strings.Builder, format, title string, rows [][]string) {
 		// AsciiDoc formatting
 		fmt.Fprintf(sb, "== %s\n\n", title)
 
-		fmt.Fprintf(sb, "[cols=\"2,1\", options=\"header\"]\n|===\n")
+		cols := "2"
+		if len(rows) > 0 {
+			cols += strings.Repeat(",1", len(rows[0])-1)
+		}
+
+		fmt.Fprintf(sb, "[cols=\"%s\", options=\"header\"]\n|===\n", cols)
 
 		for _, row := range rows {
 			fmt.Fprintf(sb, "| %s\n", strings.Join(row, " | "))
@@ -160,7 +553,13 @@ func addTable(sb *strings.Builder, format, title string, rows [][]string) {
 		fmt.Fprintf(sb, "%s:\n", title)
 
 		for _, row := range rows {
-			fmt.Fprintf(sb, " %-25s %-15s\n", row[0], row[1])
+			fmt.Fprintf(sb, " %-25s", row[0])
+
+			for _, cell := range row[1:] {
+				fmt.Fprintf(sb, " %-15s", cell)
+			}
+
+			fmt.Fprintln(sb)
 		}
 
 		fmt.Fprintln(sb)
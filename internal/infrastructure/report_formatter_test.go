@@ -0,0 +1,40 @@
+package infrastructure_test
+
+import (
+	"testing"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/abakunov/log-analyzer/internal/infrastructure"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReportFormatter_Resources(t *testing.T) {
+	metrics := domain.NewMetrics([]string{"access.log"})
+
+	records := map[string][]domain.LogRecord{
+		"/index.html": {
+			{Method: "GET", StatusCode: 200, ResponseSize: 100, RequestTime: 0.1, HasRequestTime: true},
+			{Method: "GET", StatusCode: 200, ResponseSize: 300, RequestTime: 0.4, HasRequestTime: true},
+			{Method: "POST", StatusCode: 500, ResponseSize: 300, RequestTime: 0.4, HasRequestTime: true},
+		},
+		"/robots.txt": {{Method: "GET", StatusCode: 404, ResponseSize: 0}},
+	}
+
+	for resource, resourceRecords := range records {
+		metrics.ResourceStats[resource] = domain.NewResourceStats()
+
+		for i := range resourceRecords {
+			metrics.ResourceStats[resource].Add(&resourceRecords[i])
+		}
+	}
+
+	formatter := infrastructure.ReportFormatter{Metrics: metrics}
+	report := formatter.Render("markdown")
+
+	assert.Contains(t, report, "#### Requested Resources\n\n| Resource | Count |\n| --- | --- |\n"+
+		"| /index.html | 3 |\n| /robots.txt | 1 |\n", "Resources should be ordered by requests.")
+	assert.Contains(t, report, "| Resource | Count | Methods | Codes | Error Rate | Bytes | p95 Size | Avg Latency | p95 Latency |\n"+
+		"| --- | --- | --- | --- | --- | --- | --- | --- | --- |\n"+
+		"| /index.html | 3 | GET 2, POST 1 | 200 2, 500 1 | 33.3% | 700b | 300b | 0.300s | 0.400s |\n"+
+		"| /robots.txt | 1 | GET 1 | 404 1 | 100.0% | 0b | 0b | - | - |\n")
+}
@@ -0,0 +1,152 @@
+package infrastructure
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+)
+
+// defaultS3Profile is the profile of the shared AWS files used when none is selected.
+const defaultS3Profile = "default"
+
+// LoadS3Config builds the configuration of S3 sources the way the AWS tools do. The endpoint,
+// region and profile given here take precedence; otherwise the endpoint comes from
+// AWS_ENDPOINT_URL_S3 or AWS_ENDPOINT_URL, the region from AWS_REGION, AWS_DEFAULT_REGION or the
+// shared config file, and the profile from AWS_PROFILE. Credentials are taken from
+// AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or else from the profile in the shared credentials
+// file; without any, LoadS3RoleCredentials looks for the credentials of an IAM role. A custom
+// endpoint implies path-style addressing.
+func LoadS3Config(endpoint, region, profile string) (application.S3Config, error) {
+	config := application.S3Config{
+		Endpoint: firstNonEmpty(endpoint, os.Getenv("AWS_ENDPOINT_URL_S3"), os.Getenv("AWS_ENDPOINT_URL")),
+		Region:   firstNonEmpty(region, os.Getenv("AWS_REGION"), os.Getenv("AWS_DEFAULT_REGION")),
+	}
+	config.PathStyle = config.Endpoint != ""
+
+	explicitProfile := firstNonEmpty(profile, os.Getenv("AWS_PROFILE"))
+	profile = firstNonEmpty(explicitProfile, defaultS3Profile)
+
+	if config.Region == "" {
+		settings, err := readAWSProfile(awsFile("AWS_CONFIG_FILE", "config"), configSection(profile))
+		if err != nil {
+			return config, err
+		}
+
+		config.Region = settings["region"]
+	}
+
+	if key := os.Getenv("AWS_ACCESS_KEY_ID"); key != "" {
+		config.Credentials = application.S3Credentials{
+			AccessKeyID:     key,
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		}
+
+		return config, nil
+	}
+
+	credentialsFile := awsFile("AWS_SHARED_CREDENTIALS_FILE", "credentials")
+
+	settings, err := readAWSProfile(credentialsFile, profile)
+	if err != nil {
+		return config, err
+	}
+
+	if settings == nil && explicitProfile != "" {
+		return config, fmt.Errorf("profile %s not found in %s", profile, credentialsFile)
+	}
+
+	config.Credentials = application.S3Credentials{
+		AccessKeyID:     settings["aws_access_key_id"],
+		SecretAccessKey: settings["aws_secret_access_key"],
+		SessionToken:    settings["aws_session_token"],
+	}
+
+	return config, nil
+}
+
+// awsFile returns the path of a shared AWS file, from the environment variable or in ~/.aws.
+func awsFile(envVar, name string) string {
+	if path := os.Getenv(envVar); path != "" {
+		return path
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, ".aws", name)
+}
+
+// configSection returns the section name of a profile in the shared config file, where every
+// profile but the default one is written as [profile name].
+func configSection(profile string) string {
+	if profile == defaultS3Profile {
+		return profile
+	}
+
+	return "profile " + profile
+}
+
+// readAWSProfile returns the settings of a section of a shared AWS file in INI format. It returns
+// nil if the file or the section does not exist.
+func readAWSProfile(path, section string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+
+	var (
+		settings map[string]string
+		current  string
+	)
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			current = strings.Join(strings.Fields(line[1:len(line)-1]), " ")
+			if current == section && settings == nil {
+				settings = make(map[string]string)
+			}
+		case current == section:
+			if name, value, ok := strings.Cut(line, "="); ok {
+				settings[strings.ToLower(strings.TrimSpace(name))] = strings.TrimSpace(value)
+			}
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return settings, nil
+}
+
+// firstNonEmpty returns the first of the values that is not empty.
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+
+	return ""
+}
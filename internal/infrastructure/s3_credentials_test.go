@@ -0,0 +1,120 @@
+package infrastructure_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadS3Config(t *testing.T) {
+	dir := t.TempDir()
+	credentialsFile := filepath.Join(dir, "credentials")
+	configFile := filepath.Join(dir, "config")
+
+	require.NoError(t, os.WriteFile(credentialsFile, []byte(`
+[default]
+aws_access_key_id = DEFAULTKEY
+aws_secret_access_key = defaultsecret
+
+[archive]
+aws_access_key_id=ARCHIVEKEY
+aws_secret_access_key=archivesecret
+aws_session_token=token
+`), 0o600))
+	require.NoError(t, os.WriteFile(configFile, []byte(`
+[default]
+region = eu-west-1
+
+[profile archive]
+region = eu-central-1
+`), 0o600))
+
+	tests := []struct {
+		name                      string
+		env                       map[string]string
+		endpoint, region, profile string
+		expected                  application.S3Config
+		expectErr                 bool
+	}{
+		{
+			name: "Default profile of the shared files",
+			expected: application.S3Config{
+				Region:      "eu-west-1",
+				Credentials: application.S3Credentials{AccessKeyID: "DEFAULTKEY", SecretAccessKey: "defaultsecret"},
+			},
+		},
+		{
+			name: "Profile from the environment",
+			env:  map[string]string{"AWS_PROFILE": "archive"},
+			expected: application.S3Config{
+				Region: "eu-central-1",
+				Credentials: application.S3Credentials{
+					AccessKeyID: "ARCHIVEKEY", SecretAccessKey: "archivesecret", SessionToken: "token",
+				},
+			},
+		},
+		{
+			name: "Keys and endpoint from the environment",
+			env: map[string]string{
+				"AWS_ACCESS_KEY_ID":     "ENVKEY",
+				"AWS_SECRET_ACCESS_KEY": "envsecret",
+				"AWS_REGION":            "us-west-2",
+				"AWS_ENDPOINT_URL":      "http://localhost:9000",
+			},
+			expected: application.S3Config{
+				Endpoint:    "http://localhost:9000",
+				Region:      "us-west-2",
+				PathStyle:   true,
+				Credentials: application.S3Credentials{AccessKeyID: "ENVKEY", SecretAccessKey: "envsecret"},
+			},
+		},
+		{
+			name:     "Flags take precedence",
+			env:      map[string]string{"AWS_REGION": "us-west-2", "AWS_ENDPOINT_URL_S3": "http://s3.internal"},
+			endpoint: "http://localhost:9000",
+			region:   "ap-south-1",
+			profile:  "archive",
+			expected: application.S3Config{
+				Endpoint:  "http://localhost:9000",
+				Region:    "ap-south-1",
+				PathStyle: true,
+				Credentials: application.S3Credentials{
+					AccessKeyID: "ARCHIVEKEY", SecretAccessKey: "archivesecret", SessionToken: "token",
+				},
+			},
+		},
+		{
+			name:      "Unknown profile",
+			profile:   "missing",
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{
+				"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_PROFILE",
+				"AWS_REGION", "AWS_DEFAULT_REGION", "AWS_ENDPOINT_URL", "AWS_ENDPOINT_URL_S3",
+			} {
+				t.Setenv(name, tt.env[name])
+			}
+
+			t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+			t.Setenv("AWS_CONFIG_FILE", configFile)
+
+			config, err := infrastructure.LoadS3Config(tt.endpoint, tt.region, tt.profile)
+			if tt.expectErr {
+				assert.Error(t, err)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, config)
+		})
+	}
+}
@@ -0,0 +1,189 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+)
+
+const (
+	// defaultIMDSEndpoint is the address of the EC2 instance metadata service.
+	defaultIMDSEndpoint = "http://169.254.169.254"
+
+	// imdsTimeout bounds every request to the instance metadata service, which does not answer off EC2.
+	imdsTimeout = time.Second
+
+	// stsTimeout bounds the request exchanging a web identity token for credentials.
+	stsTimeout = 10 * time.Second
+
+	// imdsTokenTTL is how long the IMDSv2 session token is requested for, in seconds.
+	imdsTokenTTL = 300
+)
+
+// assumeRoleWithWebIdentityResponse is the response of the STS AssumeRoleWithWebIdentity API.
+type assumeRoleWithWebIdentityResponse struct {
+	Credentials struct {
+		AccessKeyID     string `xml:"AccessKeyId"`
+		SecretAccessKey string `xml:"SecretAccessKey"`
+		SessionToken    string `xml:"SessionToken"`
+	} `xml:"AssumeRoleWithWebIdentityResult>Credentials"`
+}
+
+// imdsCredentials are the credentials of an instance profile returned by the instance metadata service.
+type imdsCredentials struct {
+	Code            string `json:"Code"`
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	Token           string `json:"Token"`
+}
+
+// LoadS3RoleCredentials returns the credentials of the IAM role the process runs under, for when
+// LoadS3Config finds no keys: a web identity token named by AWS_WEB_IDENTITY_TOKEN_FILE, as on EKS,
+// is exchanged through STS for the role AWS_ROLE_ARN, or else the instance profile is read from the
+// EC2 instance metadata service with IMDSv2, unless AWS_EC2_METADATA_DISABLED is true. It returns
+// empty credentials if neither is available. The credentials are not refreshed when they expire.
+func LoadS3RoleCredentials(region string) (application.S3Credentials, error) {
+	if tokenFile := os.Getenv("AWS_WEB_IDENTITY_TOKEN_FILE"); tokenFile != "" {
+		return webIdentityCredentials(tokenFile, region)
+	}
+
+	if strings.EqualFold(os.Getenv("AWS_EC2_METADATA_DISABLED"), "true") {
+		return application.S3Credentials{}, nil
+	}
+
+	return instanceProfileCredentials(firstNonEmpty(os.Getenv("AWS_EC2_METADATA_SERVICE_ENDPOINT"), defaultIMDSEndpoint))
+}
+
+// webIdentityCredentials assumes the role AWS_ROLE_ARN with the web identity token in tokenFile.
+func webIdentityCredentials(tokenFile, region string) (application.S3Credentials, error) {
+	roleARN := os.Getenv("AWS_ROLE_ARN")
+	if roleARN == "" {
+		return application.S3Credentials{}, fmt.Errorf("AWS_ROLE_ARN is required with AWS_WEB_IDENTITY_TOKEN_FILE")
+	}
+
+	token, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return application.S3Credentials{}, fmt.Errorf("failed to read web identity token: %w", err)
+	}
+
+	endpoint := "https://sts.amazonaws.com"
+	if region != "" {
+		endpoint = fmt.Sprintf("https://sts.%s.amazonaws.com", region)
+	}
+
+	query := url.Values{
+		"Action":           {"AssumeRoleWithWebIdentity"},
+		"Version":          {"2011-06-15"},
+		"RoleArn":          {roleARN},
+		"RoleSessionName":  {firstNonEmpty(os.Getenv("AWS_ROLE_SESSION_NAME"), "log-analyzer")},
+		"WebIdentityToken": {strings.TrimSpace(string(token))},
+	}
+
+	client := &http.Client{Timeout: stsTimeout}
+
+	req, err := http.NewRequest(http.MethodPost, firstNonEmpty(os.Getenv("AWS_ENDPOINT_URL_STS"), endpoint),
+		strings.NewReader(query.Encode()))
+	if err != nil {
+		return application.S3Credentials{}, fmt.Errorf("failed to assume role %s: %w", roleARN, err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	body, err := readResponse(client, req)
+	if err != nil {
+		return application.S3Credentials{}, fmt.Errorf("failed to assume role %s: %w", roleARN, err)
+	}
+
+	var response assumeRoleWithWebIdentityResponse
+	if err := xml.Unmarshal(body, &response); err != nil || response.Credentials.AccessKeyID == "" {
+		return application.S3Credentials{}, fmt.Errorf("failed to assume role %s: invalid STS response", roleARN)
+	}
+
+	return application.S3Credentials{
+		AccessKeyID:     response.Credentials.AccessKeyID,
+		SecretAccessKey: response.Credentials.SecretAccessKey,
+		SessionToken:    response.Credentials.SessionToken,
+	}, nil
+}
+
+// instanceProfileCredentials reads the credentials of the instance profile from the instance
+// metadata service at endpoint. A service that does not answer means the process does not run on
+// EC2, and empty credentials are returned.
+func instanceProfileCredentials(endpoint string) (application.S3Credentials, error) {
+	client := &http.Client{Timeout: imdsTimeout}
+	endpoint = strings.TrimSuffix(endpoint, "/")
+
+	req, err := http.NewRequest(http.MethodPut, endpoint+"/latest/api/token", http.NoBody)
+	if err != nil {
+		return application.S3Credentials{}, fmt.Errorf("invalid instance metadata endpoint: %w", err)
+	}
+
+	req.Header.Set("X-Aws-Ec2-Metadata-Token-Ttl-Seconds", strconv.Itoa(imdsTokenTTL))
+
+	token, err := readResponse(client, req)
+	if err != nil {
+		return application.S3Credentials{}, nil
+	}
+
+	get := func(path string) ([]byte, error) {
+		req, err := http.NewRequest(http.MethodGet, endpoint+"/latest/meta-data/iam/security-credentials/"+path, http.NoBody)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header.Set("X-Aws-Ec2-Metadata-Token", string(token))
+
+		return readResponse(client, req)
+	}
+
+	roles, err := get("")
+	if err != nil {
+		return application.S3Credentials{}, nil // An instance without an instance profile.
+	}
+
+	role, _, _ := strings.Cut(strings.TrimSpace(string(roles)), "\n")
+
+	body, err := get(role)
+	if err != nil {
+		return application.S3Credentials{}, fmt.Errorf("failed to read credentials of instance profile %s: %w", role, err)
+	}
+
+	var credentials imdsCredentials
+	if err := json.Unmarshal(body, &credentials); err != nil {
+		return application.S3Credentials{}, fmt.Errorf("failed to read credentials of instance profile %s: %w", role, err)
+	}
+
+	if credentials.Code != "Success" {
+		return application.S3Credentials{}, fmt.Errorf("failed to read credentials of instance profile %s: %s", role, credentials.Code)
+	}
+
+	return application.S3Credentials{
+		AccessKeyID:     credentials.AccessKeyID,
+		SecretAccessKey: credentials.SecretAccessKey,
+		SessionToken:    credentials.Token,
+	}, nil
+}
+
+// readResponse sends a request and returns the response body, or an error for a status other than 200 OK.
+func readResponse(client *http.Client, req *http.Request) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+
+	return io.ReadAll(resp.Body)
+}
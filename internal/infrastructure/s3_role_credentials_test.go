@@ -0,0 +1,90 @@
+package infrastructure_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/abakunov/log-analyzer/internal/application"
+	"github.com/abakunov/log-analyzer/internal/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadS3RoleCredentials_WebIdentity(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(tokenFile, []byte("jwt\n"), 0o600))
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.FormValue("Action") != "AssumeRoleWithWebIdentity" || r.FormValue("WebIdentityToken") != "jwt" ||
+			r.FormValue("RoleArn") != "arn:aws:iam::123456789012:role/logs" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		fmt.Fprint(w, `<AssumeRoleWithWebIdentityResponse><AssumeRoleWithWebIdentityResult><Credentials>`+
+			`<AccessKeyId>ROLEKEY</AccessKeyId><SecretAccessKey>rolesecret</SecretAccessKey>`+
+			`<SessionToken>roletoken</SessionToken></Credentials></AssumeRoleWithWebIdentityResult>`+
+			`</AssumeRoleWithWebIdentityResponse>`)
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tokenFile)
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::123456789012:role/logs")
+	t.Setenv("AWS_ENDPOINT_URL_STS", server.URL)
+
+	credentials, err := infrastructure.LoadS3RoleCredentials("eu-west-1")
+	require.NoError(t, err)
+	assert.Equal(t, application.S3Credentials{
+		AccessKeyID: "ROLEKEY", SecretAccessKey: "rolesecret", SessionToken: "roletoken",
+	}, credentials)
+
+	t.Setenv("AWS_ROLE_ARN", "")
+
+	_, err = infrastructure.LoadS3RoleCredentials("eu-west-1")
+	assert.Error(t, err, "A web identity token without a role should be rejected.")
+}
+
+func TestLoadS3RoleCredentials_InstanceProfile(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodPut && r.URL.Path == "/latest/api/token":
+			fmt.Fprint(w, "session")
+		case r.Header.Get("X-Aws-Ec2-Metadata-Token") != "session":
+			w.WriteHeader(http.StatusUnauthorized)
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/":
+			fmt.Fprint(w, "logs-reader")
+		case r.URL.Path == "/latest/meta-data/iam/security-credentials/logs-reader":
+			fmt.Fprint(w, `{"Code":"Success","AccessKeyId":"INSTANCEKEY","SecretAccessKey":"instancesecret","Token":"instancetoken"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+	t.Setenv("AWS_EC2_METADATA_SERVICE_ENDPOINT", server.URL)
+
+	credentials, err := infrastructure.LoadS3RoleCredentials("")
+	require.NoError(t, err)
+	assert.Equal(t, application.S3Credentials{
+		AccessKeyID: "INSTANCEKEY", SecretAccessKey: "instancesecret", SessionToken: "instancetoken",
+	}, credentials)
+
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+
+	credentials, err = infrastructure.LoadS3RoleCredentials("")
+	require.NoError(t, err)
+	assert.Empty(t, credentials.AccessKeyID, "The metadata service should not be asked when it is disabled.")
+
+	server.Close()
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "")
+
+	credentials, err = infrastructure.LoadS3RoleCredentials("")
+	require.NoError(t, err, "Hosts without a metadata service should get no credentials.")
+	assert.Empty(t, credentials.AccessKeyID)
+}
@@ -0,0 +1,66 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+)
+
+// LoadState reads the state of an incremental analysis from a JSON file.
+// A missing file yields an empty state, as on the first run.
+func LoadState(path string) (*domain.State, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return domain.NewState(), nil
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+
+	state := domain.NewState()
+	if err := json.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", path, err)
+	}
+
+	if state.Version != domain.StateVersion {
+		return nil, fmt.Errorf("unsupported state file version %d in %s", state.Version, path)
+	}
+
+	return state, nil
+}
+
+// SaveState writes the state to a JSON file. The file is replaced atomically, so an interrupted
+// run leaves the previous state intact.
+func SaveState(path string, state *domain.State) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to serialize state: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+
+	return nil
+}
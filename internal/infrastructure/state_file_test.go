@@ -0,0 +1,70 @@
+package infrastructure_test
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/abakunov/log-analyzer/internal/domain"
+	"github.com/abakunov/log-analyzer/internal/infrastructure"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSaveState_LoadState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state.json")
+
+	empty, err := infrastructure.LoadState(path)
+	require.NoError(t, err, "A missing state file should yield an empty state.")
+	assert.Equal(t, domain.NewState(), empty)
+
+	state := domain.NewState()
+	state.Filters = "status=5*"
+	state.Sources["access.log"] = &domain.Checkpoint{
+		Inode:         42,
+		Size:          1024,
+		ModTime:       time.Date(2024, time.November, 21, 18, 27, 47, 0, time.UTC),
+		Offset:        1000,
+		LastLineStart: 900,
+		LastLineHash:  "abc",
+	}
+
+	metrics := state.Metrics
+	metrics.FileNames = []string{"access.log"}
+	metrics.StartDate = time.Date(2024, time.November, 20, 0, 0, 0, 0, time.UTC)
+	metrics.EndDate = time.Date(2024, time.November, 21, 0, 0, 0, 0, time.UTC)
+	metrics.TotalRequests = 3
+	metrics.TotalRespSize = 600
+	metrics.AverageRespSize = 200
+	metrics.ResourceStats["/index.html"] = domain.NewResourceStats()
+	metrics.ResourceStats["/index.html"].Add(&domain.LogRecord{Method: "GET", StatusCode: 500, ResponseSize: 600})
+	metrics.StatusCodes[500] = 3
+	metrics.UniqueIPs["127.0.0.1"] = struct{}{}
+	metrics.Timings["request_time"] = &domain.TimingStats{Count: 3, Total: 0.3, Max: 0.2}
+	metrics.Timeline.Add(metrics.StartDate, "127.0.0.1", 500, 600)
+
+	for _, size := range []float64{100, 200, 300} {
+		metrics.ResponseSizes.Add(size)
+	}
+
+	require.NoError(t, infrastructure.SaveState(path, state))
+
+	loaded, err := infrastructure.LoadState(path)
+	require.NoError(t, err)
+
+	assert.Equal(t, state.Sources, loaded.Sources)
+	assert.Equal(t, state.Filters, loaded.Filters)
+	assert.Equal(t, 1, loaded.Metrics.ResourceStats["/index.html"].Requests)
+	assert.Equal(t, map[int]int{500: 1}, loaded.Metrics.ResourceStats["/index.html"].StatusCodes)
+	assert.Equal(t, metrics.StatusCodes, loaded.Metrics.StatusCodes)
+	assert.Equal(t, metrics.UniqueIPs, loaded.Metrics.UniqueIPs)
+	assert.Equal(t, metrics.Timings, loaded.Metrics.Timings)
+	assert.Equal(t, metrics.Timeline, loaded.Metrics.Timeline)
+	assert.Equal(t, metrics.TotalRequests, loaded.Metrics.TotalRequests)
+	assert.True(t, metrics.EndDate.Equal(loaded.Metrics.EndDate))
+
+	for _, q := range []float64{0, 0.5, 0.99} {
+		assert.InDelta(t, metrics.ResponseSizes.Quantile(q), loaded.Metrics.ResponseSizes.Quantile(q), 1e-9,
+			"Sketch should answer the same quantiles after a round trip.")
+	}
+}